@@ -0,0 +1,162 @@
+// Package main is the entry point for the UniGrades application.
+package main
+
+import (
+	// Standard library imports
+	"bytes"   // Comparison of encryption salts
+	"context" // Background context for the restore
+	"flag"    // Command-line flag parsing
+	"fmt"     // Formatted I/O
+	"log"     // Logging support
+	"os"      // Archive files
+	"sort"    // Ordering of the recorded settings
+	"strings" // Rendering of the recorded settings
+	"time"    // Default archive name
+
+	// Internal packages
+	"UniGrades/internal/api" // Course storage operations
+)
+
+// runBackup implements "unigrades backup [-o FILE]": it writes every university's
+// courses and history, and the options UniGrades was started with, to an archive.
+// An existing file is never overwritten.
+func runBackup(args []string, store api.CourseStore, history api.HistoryStore) {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := fs.String("o", "unigrades-backup-"+time.Now().Format("20060102-150405")+".json", "path of the archive to write")
+	fs.Parse(args)
+
+	backup, err := api.CreateBackup(context.Background(), store, history, settings())
+	if api.IsWarning(err) {
+		fmt.Printf("Warning: %v\n", err)
+	} else if err != nil {
+		log.Fatalf("Failed to back up: %v", err)
+	}
+
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		log.Fatalf("Failed to create the archive: %v", err)
+	}
+	if err := api.WriteBackup(f, backup); err != nil {
+		f.Close()
+		os.Remove(*out)
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Failed to write the archive: %v", err)
+	}
+
+	courses, entries := 0, 0
+	for _, u := range backup.Data.Universities {
+		courses += len(u.Courses)
+		entries += len(u.History)
+	}
+	fmt.Printf("Backed up %d course(s) and %d history entries of %d universities to %s\n",
+		courses, entries, len(backup.Data.Universities), *out)
+}
+
+// runRestore implements "unigrades restore [-mode merge|replace] FILE": it verifies the
+// archive and writes its courses and history back. Merging keeps everything that is
+// already stored; replacing empties each archived university first.
+// The restore isn't bounded by a timeout, since it writes every archived course.
+// Grades sealed with a passphrase under the archived salt are decrypted with that salt
+// first, so the store seals them again with its own.
+func runRestore(args []string, store api.CourseStore, history api.HistoryStore, c *api.GradeCipher) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	mode := fs.String("mode", "merge", "merge: add what is missing; replace: discard the stored courses and history first")
+	fs.Parse(args)
+	if fs.NArg() != 1 || (*mode != "merge" && *mode != "replace") {
+		log.Fatal("Usage: unigrades restore [-mode merge|replace] FILE")
+	}
+
+	courses, ok := store.(api.CourseImporter)
+	if !ok {
+		log.Fatal("The selected store can't restore backups")
+	}
+	entries, ok := history.(api.HistoryImporter)
+	if !ok {
+		log.Fatal("The selected history store can't restore backups")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to open the archive: %v", err)
+	}
+	backup, err := api.ReadBackup(f)
+	f.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if salt := backup.Data.EncryptionSalt; c != nil && c.Salt() != nil && salt != nil && !bytes.Equal(salt, c.Salt()) {
+		archived, err := api.NewPassphraseCipher(os.Getenv("UNIGRADES_PASSPHRASE"), salt)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if backup, err = api.OpenBackup(backup, archived); err != nil {
+			log.Fatalf("The archive was encrypted with another passphrase: %v", err)
+		}
+	}
+	fmt.Printf("Restoring the backup of %s (schema version %d)\n", backup.CreatedAt.Local().Format("2006-01-02 15:04"), backup.SchemaVersion)
+	if s := formatSettings(backup.Data.Settings); s != "" {
+		fmt.Printf("It was taken with: %s\n", s)
+	}
+
+	report, err := api.RestoreBackup(context.Background(), backup, courses, entries, *mode == "replace")
+	if len(report.Results) > 0 {
+		fmt.Println(report)
+	}
+	if err != nil {
+		log.Fatalf("Failed to restore: %v", err)
+	}
+}
+
+// settings returns the command-line options that were set explicitly, by flag name.
+func settings() map[string]string {
+	set := map[string]string{}
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+	return set
+}
+
+// formatSettings renders settings as command-line options, e.g. "-store=file -trash-retention=168h0m0s".
+func formatSettings(settings map[string]string) string {
+	options := make([]string, 0, len(settings))
+	for name, value := range settings {
+		options = append(options, fmt.Sprintf("-%s=%s", name, value))
+	}
+	sort.Strings(options)
+	return strings.Join(options, " ")
+}
+
+// runCommand runs the backup, restore, export or token subcommand given after the flags, if any, on
+// the unwrapped store: restored courses must keep their IDs and revisions, and restoring records
+// no history of its own. Reads of a backup or export are bounded by the load timeout like any other read.
+// Backups hold the grades as they are stored, sealed or not. With a keyfile or passphrase,
+// restored grades that were archived before encryption was turned on are sealed before they
+// are written. None of the commands migrate the stored courses or seal their grades first.
+// Returns false if there is no such subcommand, so the terminal UI or the server should start.
+func runCommand(base api.CourseStore, history api.HistoryStore, tokens api.TokenStore, t api.Timeouts, keyFile string) bool {
+	switch flag.Arg(0) {
+	case "", "serve":
+		return false
+	case "backup":
+		runBackup(flag.Args()[1:], api.WithTimeouts(base, t), history)
+	case "restore":
+		c := openCipher(keyFile, base, "", t.Load)
+		if c != nil {
+			base, history = api.WithEncryption(base, c), api.WithHistoryEncryption(history, c)
+		}
+		runRestore(flag.Args()[1:], base, history, c)
+	case "export":
+		store := api.WithTimeouts(base, t)
+		if c := openCipher(keyFile, base, "", t.Load); c != nil {
+			store = api.WithEncryption(store, c)
+		}
+		runExport(flag.Args()[1:], store)
+	case "token":
+		runToken(flag.Args()[1:], tokens, t.Load)
+	default:
+		log.Fatalf("Unknown command %q. Valid commands are: backup, restore, export, serve, token", flag.Arg(0))
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+// Package main is the entry point for the UniGrades application.
+package main
+
+import (
+	// Standard library imports
+	"context" // Background context for the export
+	"flag"    // Command-line flag parsing
+	"fmt"     // Formatted I/O
+	"log"     // Logging support
+	"os"      // Export files and standard output
+	"slices"  // University lookup
+
+	// Internal packages
+	"UniGrades/internal/api"        // Course storage operations and column registry
+	"UniGrades/internal/university" // University names
+)
+
+// runExport implements "unigrades export [-university NAME] [-columns LIST] [-o FILE]": it writes
+// the active courses of a university as CSV, in the columns of the column registry, to standard
+// output or to a new file. Grades are exported decrypted; warnings go to standard error.
+func runExport(args []string, store api.CourseStore) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	uni := fs.String("university", university.Names()[0], "university whose courses are exported")
+	columnList := fs.String("columns", "", "comma-separated columns to export, e.g. Name,Grade,Points (default: Name,Year,Grade,ECTS)")
+	out := fs.String("o", "", "path of the CSV file to write (default: standard output)")
+	fs.Parse(args)
+
+	if !slices.Contains(university.Names(), *uni) {
+		log.Fatalf("Unknown university %q", *uni)
+	}
+	columns, err := api.ParseColumns(*columnList)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	courses, err := store.GetAllCourses(context.Background(), *uni)
+	if api.IsWarning(err) {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else if err != nil {
+		log.Fatalf("Failed to export: %v", err)
+	}
+
+	if *out == "" {
+		if err := api.WriteCSV(os.Stdout, columns, courses); err != nil {
+			log.Fatalf("Failed to write the export: %v", err)
+		}
+		return
+	}
+
+	// Like backups, an existing file is never overwritten
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		log.Fatalf("Failed to create the export: %v", err)
+	}
+	if err := api.WriteCSV(f, columns, courses); err != nil {
+		f.Close()
+		os.Remove(*out)
+		log.Fatalf("Failed to write the export: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Failed to write the export: %v", err)
+	}
+	fmt.Printf("Exported %d course(s) of %s to %s\n", len(api.ActiveCourses(courses)), *uni, *out)
+}
@@ -1,147 +1,107 @@
-// Package api provides MongoDB database operations for managing course information.
-// It handles CRUD operations (Create, Read, Update, Delete) for courses stored in MongoDB,
-// including operations like retrieving all courses, getting table headers, adding courses,
-// updating course details, and deleting courses.
+// Package api provides database operations for managing course information.
+// It defines the CourseStore interface for CRUD operations (Create, Read, Update, Delete)
+// on courses, together with a MongoDB-backed implementation and an in-memory implementation
+// that can be used without a live database connection.
 package api
 
 import (
 	// Standard library imports for core functionality
-	"context"       // Used for context management in MongoDB operations
-	"encoding/json" // JSON marshaling/unmarshaling utilities
-	"fmt"           // Formatted I/O package
-	"log"           // Logging utilities
-	"os"            // Operating system functionality
-	"strconv"       // String conversion utilities
+	"context" // Used for context management in MongoDB operations
+	"fmt"     // Formatted I/O package
+	"math"    // Checks for non-finite numbers
+	"slices"  // Slice search helpers
+	"strconv" // String conversion utilities
+	"strings" // String manipulation
+	"time"    // Deletion timestamps
 
 	// Third-party packages
-	"github.com/joho/godotenv"                     // Loads environment variables from .env files
-	"go.mongodb.org/mongo-driver/v2/bson"          // BSON encoding/decoding for MongoDB
-	"go.mongodb.org/mongo-driver/v2/mongo"         // MongoDB driver
-	"go.mongodb.org/mongo-driver/v2/mongo/options" // MongoDB connection options
+	"go.mongodb.org/mongo-driver/v2/bson" // BSON encoding/decoding for MongoDB
 )
 
-// GetAllCourses retrieves all course documents from the MongoDB database.
-// It queries the "TUe" collection in the "CourseInfo" database and returns
-// all documents as a slice of BSON maps.
-//
-// Parameters:
-//
-//	client: MongoDB client connection
-//
-// Returns:
-//
-//	A slice of bson.M (BSON maps) containing all course documents.
-//	Panics if the database query fails.
-func GetAllCourses(client *mongo.Client) []bson.M {
-	// Access the "TUe" collection from the "CourseInfo" database
-	coll := client.Database("CourseInfo").Collection("TUe")
-
-	// Execute a query to find all documents (empty filter returns all documents)
-	cursor, err := coll.Find(context.TODO(), bson.D{})
-	if err != nil {
-		panic(err)
-	}
-	defer cursor.Close(context.TODO())
-
-	// Decode all cursor results into a slice of BSON maps
-	var results []bson.M
-	if err := cursor.All(context.TODO(), &results); err != nil {
-		panic(err)
-	}
+// CourseStore defines the storage operations the screens and the HTTP server need.
+// Implementations decide where the courses actually live (MongoDB, memory, ...),
+// so callers can swap backends without touching any UI or handler code.
+// Every operation is scoped to a single university, identified by its display name
+// (e.g. "TU/e"), so each university keeps its own independent set of courses.
+// Errors wrap ErrNotFound, ErrValidation, ErrConflict or ErrUnavailable where applicable.
+// Every change increments the revision of the course. DeleteCourse and UpdateCourse take
+// the revision the caller last saw and fail with a *ConflictError if the course has been
+// changed since, so concurrent sessions can't silently overwrite each other; passing
+// AnyRevision skips the check. Every operation takes a context so callers can cancel it or bound it with a deadline.
+type CourseStore interface {
+	// GetAllCourses returns every active (not trashed) course of the university. Stored documents that
+	// cannot be decoded into a valid Course are skipped and reported through a
+	// *MalformedError, which is returned together with the valid courses.
+	GetAllCourses(ctx context.Context, uni string) ([]Course, error)
+	// GetCourse returns the active course with the given ID (in hex format).
+	GetCourse(ctx context.Context, uni, id string) (Course, error)
+	// GetStats returns the course count, average grades and ECTS totals of the active
+	// courses, overall and per year. Stores compute them where the data lives, so the
+	// courses don't have to be loaded; every store gives the same results.
+	GetStats(ctx context.Context, uni string) (CourseStats, error)
+	// AddCourse inserts a new course and returns its ID in hex format.
+	// A course without an ID is assigned a new one; a course that already has an ID keeps it,
+	// which lets a deleted course be restored under its old ID.
+	AddCourse(ctx context.Context, uni string, course Course) (string, error)
+	// DeleteCourse moves the course with the given ID and revision to the trash.
+	// Trashed courses are left out of every other read until restored or purged.
+	DeleteCourse(ctx context.Context, uni, id string, revision int64) error
+	// GetTrashedCourses returns the courses in the trash.
+	GetTrashedCourses(ctx context.Context, uni string) ([]Course, error)
+	// RestoreCourse moves the trashed course with the given ID back to the active courses.
+	RestoreCourse(ctx context.Context, uni, id string) error
+	// PurgeTrash permanently removes the courses trashed before the given time
+	// and returns how many were removed.
+	PurgeTrash(ctx context.Context, uni string, before time.Time) (int, error)
+	// UpdateCourse sets one or more fields of the course with the given ID and revision
+	// in a single write. The updates map field names to their new values; all values are
+	// validated before anything is written, so either every field changes or none does.
+	UpdateCourse(ctx context.Context, uni, id string, revision int64, updates map[string]string) error
+}
 
-	// Optional debug code (commented out) that could print JSON representation of results
-	/* jsonData, err := json.MarshalIndent(results, "", "    ")
-	if err != nil {
-		panic(err)
-	}
-	fmt.Printf("%s\n", jsonData) */
+// AnyRevision tells DeleteCourse and UpdateCourse to change the course whatever its revision.
+const AnyRevision int64 = 0
 
-	return results
+// Unwrapper is implemented by CourseStore decorators to expose the store they wrap,
+// so optional capabilities such as ChangeWatcher can be found behind them.
+type Unwrapper interface {
+	// Unwrap returns the wrapped store.
+	Unwrap() CourseStore
 }
 
-// GetTableHeaders retrieves the field names of the first course document in the database.
-// This is useful for determining the structure/schema of course documents and
-// extracting column headers for display in tables. The _id field is excluded.
+// FindStore returns the first store of type T in a chain of decorators, starting with
+// store itself and following Unwrap. It finds optional capabilities (e.g. ChangeWatcher)
+// of a store that has been wrapped with WithHistory or WithTimeouts.
 //
 // Parameters:
 //
-//	client: MongoDB client connection
+//	store: The outermost store
 //
 // Returns:
 //
-//	A slice of strings containing the field names from the first document.
-//	Returns nil if no courses are found in the database.
-func GetTableHeaders(client *mongo.Client) []string {
-	// Access the "TUe" collection from the "CourseInfo" database
-	coll := client.Database("CourseInfo").Collection("TUe")
-
-	var result bson.D
-	// Configure projection to exclude the MongoDB _id field
-	opts := options.FindOne().SetProjection(bson.D{{Key: "_id", Value: 0}})
-
-	// Retrieve the first document from the collection
-	err := coll.FindOne(context.TODO(), bson.D{}, opts).Decode(&result)
-
-	// Handle case where no documents exist in the collection
-	if err == mongo.ErrNoDocuments {
-		fmt.Println("Cannot retrieve table headers: No courses were found in the database.")
-		return nil
-	}
-	if err != nil {
-		panic(err)
-	}
-
-	// Extract field names from the BSON document
-	headers := make([]string, 0, len(result))
-	for _, elem := range result {
-		headers = append(headers, elem.Key)
-	}
-
-	return headers
-}
-
-// getCourseDataByName queries the database for a course by its name and prints
-// the course data as formatted JSON. This is a private helper function (lowercase name).
-//
-// Parameters:
-//
-//	client: MongoDB client connection
-//	name: The name of the course to search for
-//
-// Note: This function prints to stdout rather than returning a value. It's typically
-// used for debugging or manual data inspection.
-func getCourseDataByName(client *mongo.Client, name string) {
-	// Access the "TUe" collection from the "CourseInfo" database
-	coll := client.Database("CourseInfo").Collection("TUe")
-
-	var result bson.M
-	// Query for a single document matching the given course name
-	err := coll.FindOne(context.TODO(), bson.D{{"Name", name}}).
-		Decode(&result)
-
-	// Handle case where no document with the given name exists
-	if err == mongo.ErrNoDocuments {
-		fmt.Printf("No course was found with the name %s\n", name)
-		return
-	}
-	if err != nil {
-		panic(err)
-	}
-
-	// Marshal the result to formatted JSON for pretty printing
-	jsonData, err := json.MarshalIndent(result, "", "    ")
-	if err != nil {
-		panic(err)
+//	The store as T, and false if no store in the chain is a T.
+func FindStore[T any](store CourseStore) (T, bool) {
+	for store != nil {
+		if found, ok := store.(T); ok {
+			return found, true
+		}
+		unwrapper, ok := store.(Unwrapper)
+		if !ok {
+			break
+		}
+		store = unwrapper.Unwrap()
 	}
-
-	// Print the formatted JSON to stdout
-	fmt.Printf("%s\n", jsonData)
+	var zero T
+	return zero, false
 }
 
 // Course represents a university course with its core information.
 // This struct maps to course documents in the MongoDB database with BSON tags
 // defining how fields are serialized/deserialized.
 type Course struct {
+	// ID is the unique identifier assigned by the store when the course is added
+	ID bson.ObjectID `bson:"_id,omitempty" json:"id,omitzero"`
+
 	// Name is the identifier for the course (e.g., "DZC10_Game_Design_I")
 	Name string `bson:"Name"`
 
@@ -151,183 +111,246 @@ type Course struct {
 	// Grade is the numerical grade/mark received for the course
 	Grade float64 `bson:"Grade"`
 
-	// ECTS is the number of European Credit Transfer System points earned
-	ECTS int `bson:"ECTS"`
+	// SealedGrade is the encrypted grade of a course stored through WithEncryption;
+	// the stored Grade is then 0. Reads through WithEncryption clear it again.
+	SealedGrade string `bson:"SealedGrade,omitempty" json:",omitempty"`
+
+	// ECTS is the number of European Credit Transfer System points earned.
+	// It is a decimal number because some courses award half credits (e.g. 2.5).
+	ECTS float64 `bson:"ECTS"`
+
+	// Revision counts the changes made to the course, starting at 1 when it is added.
+	// DeleteCourse and UpdateCourse use it to detect concurrent changes.
+	Revision int64 `bson:"Revision"`
+
+	// University is the university the course belongs to, set by the store when it is added
+	University string `bson:"University,omitempty" json:",omitempty"`
+
+	// DeletedAt is when the course was moved to the trash; nil for active courses
+	DeletedAt *time.Time `bson:"DeletedAt,omitempty" json:",omitempty"`
 }
 
-// AddCourse inserts a new course document into the MongoDB database.
-// It creates a new entry in the "TUe" collection under the "CourseInfo" database.
-//
-// Parameters:
-//
-//	client: MongoDB client connection
-//	course: A Course struct containing the course information to be added
-//
-// Returns:
-//
-//	A string containing the MongoDB ObjectID of the newly inserted document (in hex format).
-//	An error if insertion fails, wrapped with context about the failure.
-func AddCourse(client *mongo.Client, course Course) (string, error) {
-	// Access the "TUe" collection from the "CourseInfo" database
-	coll := client.Database("CourseInfo").Collection("TUe")
-
-	// Insert the course document into the collection
-	result, err := coll.InsertOne(context.TODO(), course)
-	if err != nil {
-		return "", fmt.Errorf("failed to insert course: %w", err)
+// Trashed reports whether the course has been deleted and sits in the trash.
+func (c Course) Trashed() bool {
+	return c.DeletedAt != nil
+}
+
+// ActiveCourses returns the courses that are not in the trash.
+// Computations and renderers use it so trashed courses never count towards any statistic.
+func ActiveCourses(courses []Course) []Course {
+	active := make([]Course, 0, len(courses))
+	for _, c := range courses {
+		if !c.Trashed() {
+			active = append(active, c)
+		}
 	}
+	return active
+}
+
+// courseFields lists the editable course fields in their display order.
+var courseFields = []string{"Name", "Year", "Grade", "ECTS"}
+
+// storedFields lists every field a store can write: the editable fields, plus the sealed
+// grade, which only WithEncryption writes, through updateStored. UpdateCourse accepts courseFields only.
+var storedFields = append(slices.Clone(courseFields), "SealedGrade")
 
-	// Return the automatically generated MongoDB ObjectID as a hexadecimal string
-	return result.InsertedID.(bson.ObjectID).Hex(), nil
+// Value returns the value of the course field with the given name.
+// Returns false if the course has no such field.
+func (c Course) Value(field string) (interface{}, bool) {
+	switch field {
+	case "_id":
+		return c.ID.Hex(), true
+	case "ID":
+		return c.ShortID(), true
+	case "Name":
+		return c.Name, true
+	case "Year":
+		return c.Year, true
+	case "Grade":
+		return c.Grade, true
+	case "ECTS":
+		return c.ECTS, true
+	default:
+		return nil, false
+	}
+}
+
+// MalformedDocument describes a stored course document that could not be used.
+type MalformedDocument struct {
+	// ID is the document's identifier, or empty if it could not be read
+	ID string
+	// Reason explains why the document was rejected
+	Reason string
 }
 
-// DeleteCourse removes a course from the MongoDB database by matching on its name.
-// If no course with the specified name exists, an error is returned.
+// MalformedError reports stored course documents that could not be decoded into a valid Course.
+// It is returned by GetAllCourses alongside the courses that were read successfully,
+// and matches ErrValidation with errors.Is.
+type MalformedError struct {
+	// Documents lists every rejected document
+	Documents []MalformedDocument
+}
+
+// Error summarizes the rejected documents.
+func (e *MalformedError) Error() string {
+	reasons := make([]string, 0, len(e.Documents))
+	for _, d := range e.Documents {
+		id := d.ID
+		if id == "" {
+			id = "unknown id"
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %s", id, d.Reason))
+	}
+	return fmt.Sprintf("%d malformed course document(s) skipped (%s)", len(e.Documents), strings.Join(reasons, "; "))
+}
+
+// Unwrap lets errors.Is match a MalformedError against ErrValidation.
+func (e *MalformedError) Unwrap() error {
+	return ErrValidation
+}
+
+// validateCourse checks that all fields of a course hold acceptable values.
+// Returns an error wrapping ErrValidation describing the first invalid field.
+func validateCourse(course Course) error {
+	switch {
+	case course.Name == "":
+		return fmt.Errorf("%w: Name must not be empty", ErrValidation)
+	case course.Year < 1:
+		return fmt.Errorf("%w: Year must be at least 1", ErrValidation)
+	case math.IsNaN(course.Grade) || math.IsInf(course.Grade, 0):
+		// NaN passes every comparison below, and neither can be encoded as JSON
+		return fmt.Errorf("%w: Grade must be a finite number", ErrValidation)
+	case math.IsNaN(course.ECTS) || math.IsInf(course.ECTS, 0):
+		return fmt.Errorf("%w: ECTS must be a finite number", ErrValidation)
+	case course.Grade < 0 || course.Grade > 10:
+		return fmt.Errorf("%w: Grade must be between 0 and 10", ErrValidation)
+	case course.ECTS < 0:
+		return fmt.Errorf("%w: ECTS must not be negative", ErrValidation)
+	}
+	return nil
+}
+
+// parseUpdates converts every field update to its stored type.
+// Fields are returned in the canonical courseFields order so the resulting $set is deterministic.
 //
 // Parameters:
 //
-//	client: MongoDB client connection
-//	courseName: The exact name of the course to delete
+//	updates: The field names (Name, Year, Grade or ECTS) mapped to their new values as strings
 //
 // Returns:
 //
-//	An error if deletion fails or if the course is not found. Returns nil on success.
-func DeleteCourse(client *mongo.Client, courseName string) error {
-	// Access the "TUe" collection from the "CourseInfo" database
-	coll := client.Database("CourseInfo").Collection("TUe")
-
-	// Execute a delete operation targeting the course with the matching name
-	result, err := coll.DeleteOne(context.TODO(), bson.D{{Key: "Name", Value: courseName}})
-	if err != nil {
-		return fmt.Errorf("failed to delete course: %w", err)
+//	The converted updates, or an error wrapping ErrValidation if there are no updates
+//	or any field or value is invalid. Nothing is returned unless every update is valid.
+func parseUpdates(updates map[string]string) (bson.D, error) {
+	return parseFields(updates, courseFields)
+}
+
+// parseStoredUpdates is parseUpdates for updateStored, which may also write the sealed grade.
+func parseStoredUpdates(updates map[string]string) (bson.D, error) {
+	return parseFields(updates, storedFields)
+}
+
+// parseFields converts the updates of the allowed fields, in the order of allowed.
+func parseFields(updates map[string]string, allowed []string) (bson.D, error) {
+	if len(updates) == 0 {
+		return nil, fmt.Errorf("%w: no fields to update", ErrValidation)
 	}
 
-	// Check if the course was actually found and deleted
-	if result.DeletedCount == 0 {
-		return fmt.Errorf("course '%s' not found", courseName)
+	// Reject unknown fields before converting, so the error names the offending field
+	for field := range updates {
+		if !slices.Contains(allowed, field) {
+			return nil, fmt.Errorf("%w: invalid field: %s. Valid fields are: %s", ErrValidation, field, strings.Join(courseFields, ", "))
+		}
 	}
 
-	return nil
+	result := bson.D{}
+	for _, field := range allowed {
+		value, ok := updates[field]
+		if !ok {
+			continue
+		}
+		converted, err := parseFieldValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, bson.E{Key: field, Value: converted})
+	}
+	return result, nil
 }
 
-// UpdateCourse modifies a single field of a course document in the MongoDB database.
-// It performs type validation and conversion based on the field being updated.
-// Supported fields are: Name (string), Grade (float), Year (int), and ECTS (int).
+// setField assigns a value converted by parseFieldValue to the matching Course field.
+func (c *Course) setField(field string, value interface{}) {
+	switch field {
+	case "Name":
+		c.Name = value.(string)
+	case "Year":
+		c.Year = value.(int)
+	case "Grade":
+		c.Grade = value.(float64)
+	case "ECTS":
+		c.ECTS = value.(float64)
+	case "SealedGrade":
+		c.SealedGrade = value.(string)
+	}
+}
+
+// parseFieldValue converts a string value to the type stored for the given field.
+// Supported fields are: Name (string), Grade (float), Year (int), and ECTS (float).
+// Converted values are checked against the same rules as validateCourse.
 //
 // Parameters:
 //
-//	client: MongoDB client connection
-//	courseName: The exact name of the course to update
-//	field: The field name to update (Name, Grade, Year, or ECTS)
-//	value: The new value as a string (will be converted to appropriate type)
+//	field: The field name (Name, Grade, Year, or ECTS)
+//	value: The new value as a string
 //
 // Returns:
 //
-//	An error if the update fails, the field is invalid, the value cannot be converted,
-//	or the course is not found. Returns nil on success.
-func UpdateCourse(client *mongo.Client, courseName, field, value string) error {
-	// Access the "TUe" collection from the "CourseInfo" database
-	coll := client.Database("CourseInfo").Collection("TUe")
-
-	// Convert the string value to the appropriate type based on the field
-	var updateValue interface{}
-	var err error
+//	The converted value, or an error wrapping ErrValidation if the field is invalid
+//	or the value cannot be converted.
+func parseFieldValue(field, value string) (interface{}, error) {
+	// Start from a valid course so only the field being set is checked
+	check := Course{Name: "-", Year: 1}
 
+	var result interface{}
 	switch field {
 	case "Name":
 		// Name field is stored as a string, use value as-is
-		updateValue = value
+		check.Name = value
+		result = value
 	case "Grade":
 		// Grade field must be converted to float64
-		updateValue, err = strconv.ParseFloat(value, 64)
+		grade, err := strconv.ParseFloat(value, 64)
 		if err != nil {
-			return fmt.Errorf("Grade must be a number")
+			return nil, fmt.Errorf("%w: Grade must be a number", ErrValidation)
 		}
+		check.Grade = grade
+		result = grade
 	case "Year":
 		// Year field must be converted to integer
 		year, err := strconv.Atoi(value)
 		if err != nil {
-			return fmt.Errorf("Year must be an integer")
+			return nil, fmt.Errorf("%w: Year must be an integer", ErrValidation)
 		}
-		updateValue = year
+		check.Year = year
+		result = year
 	case "ECTS":
-		// ECTS field must be converted to integer
-		ects, err := strconv.Atoi(value)
+		// ECTS field must be converted to float64
+		ects, err := strconv.ParseFloat(value, 64)
 		if err != nil {
-			return fmt.Errorf("ECTS must be an integer")
+			return nil, fmt.Errorf("%w: ECTS must be a number", ErrValidation)
 		}
-		updateValue = ects
+		check.ECTS = ects
+		result = ects
+	case "SealedGrade":
+		// The sealed grade is ciphertext produced by WithEncryption, stored as-is
+		result = value
 	default:
 		// Field name is not recognized
-		return fmt.Errorf("invalid field: %s. Valid fields are: Name, Year, Grade, ECTS", field)
-	}
-
-	// Execute the update operation on the document matching the course name
-	result, err := coll.UpdateOne(
-		context.TODO(),
-		bson.D{{Key: "Name", Value: courseName}}, // Filter: match by course name
-		bson.D{{Key: "$set", Value: bson.D{{Key: field, Value: updateValue}}}}, // Update: set the field to new value
-	)
-	if err != nil {
-		return fmt.Errorf("failed to update course: %w", err)
+		return nil, fmt.Errorf("%w: invalid field: %s. Valid fields are: Name, Year, Grade, ECTS", ErrValidation, field)
 	}
 
-	// Check if the course was actually found (MatchedCount > 0 means a document was matched)
-	if result.MatchedCount == 0 {
-		return fmt.Errorf("course '%s' not found", courseName)
+	if err := validateCourse(check); err != nil {
+		return nil, err
 	}
-
-	return nil
-}
-
-// Run initializes and manages the MongoDB database connection.
-// It loads the MongoDB URI from environment variables and establishes a connection.
-// This function serves as a setup routine and should be called before performing
-// any database operations. The MongoDB client connection is properly deferred for cleanup.
-//
-// Environment Requirements:
-//
-//	MONGODB_URI: Environment variable containing the MongoDB connection string.
-//	             If not set, the function will terminate with a fatal error.
-//
-// Note: This function currently contains commented-out debug operations that
-// could be used to test database connectivity (getCourseDataByName, GetAllCourses).
-func Run() {
-	// Load environment variables from the .env file in the current directory
-	godotenv.Load(".env")
-
-	// Retrieve the MongoDB connection URI from environment variables
-	uri := os.Getenv("MONGODB_URI")
-
-	// Documentation reference for MongoDB Go driver
-	docs := "www.mongodb.com/docs/drivers/go/current/"
-
-	// Validate that the MONGODB_URI environment variable is set
-	if uri == "" {
-		log.Fatal("Set your 'MONGODB_URI' environment variable. " +
-			"See: " + docs +
-			"usage-examples/#environment-variable")
-	}
-
-	// Establish a connection to the MongoDB server using the provided URI
-	client, err := mongo.Connect(options.Client().
-		ApplyURI(uri))
-	if err != nil {
-		panic(err)
-	}
-
-	// Ensure the MongoDB connection is properly closed when the function exits
-	defer func() {
-		if err := client.Disconnect(context.TODO()); err != nil {
-			panic(err)
-		}
-	}()
-
-	// Optional debug operations (currently commented out)
-	// Uncomment to test database connectivity and retrieve sample data:
-	// getCourseDataByName(client, "DZC10_Game_Design_I")
-	// fmt.Println(GetAllCourses(client))
-
-	// Retrieve and set up table headers from the database
-	GetTableHeaders(client)
+	return result, nil
 }
@@ -0,0 +1,198 @@
+package api
+
+import (
+	// Standard library imports
+	"context" // Background context for store calls
+	"errors"  // Error inspection
+	"math"    // Non-finite numbers
+	"testing" // Test framework
+	"time"    // Deletion time of the trashed course
+)
+
+// TestAddCourseValidation checks which courses AddCourse rejects, as the /add command sends them.
+func TestAddCourseValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		course Course
+		// wantErr is true if the course must be rejected with ErrValidation
+		wantErr bool
+	}{
+		{name: "valid", course: Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5}},
+		{name: "empty name", course: Course{Year: 1, Grade: 7, ECTS: 5}, wantErr: true},
+		{name: "year zero", course: Course{Name: "Calculus", Grade: 7, ECTS: 5}, wantErr: true},
+		{name: "grade too high", course: Course{Name: "Calculus", Year: 1, Grade: 11, ECTS: 5}, wantErr: true},
+		{name: "negative ECTS", course: Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: -1}, wantErr: true},
+		{name: "grade NaN", course: Course{Name: "Calculus", Year: 1, Grade: math.NaN(), ECTS: 5}, wantErr: true},
+		{name: "grade negative infinity", course: Course{Name: "Calculus", Year: 1, Grade: math.Inf(-1), ECTS: 5}, wantErr: true},
+		{name: "ECTS NaN", course: Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: math.NaN()}, wantErr: true},
+		{name: "ECTS infinity", course: Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: math.Inf(1)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMemoryStore().AddCourse(context.Background(), "TU/e", tt.course)
+			if tt.wantErr {
+				if !errors.Is(err, ErrValidation) {
+					t.Errorf("AddCourse() = %v, want ErrValidation", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("AddCourse() = %v", err)
+			}
+		})
+	}
+}
+
+// TestUpdateCourseValidation checks which field updates UpdateCourse rejects, as the /edit
+// command and PATCH /courses/{id} send them.
+func TestUpdateCourseValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates map[string]string
+		// wantErr is true if the updates must be rejected with ErrValidation
+		wantErr bool
+	}{
+		{name: "valid", updates: map[string]string{"Grade": "8.5", "ECTS": "6"}},
+		{name: "no fields", updates: map[string]string{}, wantErr: true},
+		{name: "unknown field", updates: map[string]string{"Teacher": "Smith"}, wantErr: true},
+		{name: "grade not a number", updates: map[string]string{"Grade": "high"}, wantErr: true},
+		{name: "grade NaN", updates: map[string]string{"Grade": "NaN"}, wantErr: true},
+		{name: "grade infinity", updates: map[string]string{"Grade": "+Inf"}, wantErr: true},
+		{name: "ECTS NaN", updates: map[string]string{"ECTS": "NaN"}, wantErr: true},
+		{name: "ECTS infinity", updates: map[string]string{"ECTS": "+Inf"}, wantErr: true},
+		{name: "grade NaN with valid ECTS", updates: map[string]string{"Grade": "NaN", "ECTS": "5"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStore()
+			id, err := store.AddCourse(ctx, "TU/e", Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5})
+			if err != nil {
+				t.Fatalf("AddCourse() = %v", err)
+			}
+
+			err = store.UpdateCourse(ctx, "TU/e", id, 0, tt.updates)
+			if tt.wantErr {
+				if !errors.Is(err, ErrValidation) {
+					t.Errorf("UpdateCourse() = %v, want ErrValidation", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("UpdateCourse() = %v", err)
+			}
+		})
+	}
+}
+
+// TestMemoryStoreNameAndYearUnique checks that, like MongoDB's unique name index, the memory
+// store accepts retakes in another year but no two active courses with the same name and year.
+func TestMemoryStoreNameAndYearUnique(t *testing.T) {
+	tests := []struct {
+		name string
+		// change is made on a store holding the active course Calculus of year 1, with ID id
+		change func(ctx context.Context, store *MemoryStore, id string) error
+		// wantErr is true if the change must be rejected with ErrValidation
+		wantErr bool
+	}{
+		{
+			name: "retake in another year",
+			change: func(ctx context.Context, store *MemoryStore, id string) error {
+				_, err := store.AddCourse(ctx, "TU/e", Course{Name: "Calculus", Year: 2, Grade: 8, ECTS: 5})
+				return err
+			},
+		},
+		{
+			name: "same name and year",
+			change: func(ctx context.Context, store *MemoryStore, id string) error {
+				_, err := store.AddCourse(ctx, "TU/e", Course{Name: "Calculus", Year: 1, Grade: 8, ECTS: 5})
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "same name and year after trashing",
+			change: func(ctx context.Context, store *MemoryStore, id string) error {
+				if err := store.DeleteCourse(ctx, "TU/e", id, AnyRevision); err != nil {
+					return err
+				}
+				_, err := store.AddCourse(ctx, "TU/e", Course{Name: "Calculus", Year: 1, Grade: 8, ECTS: 5})
+				return err
+			},
+		},
+		{
+			name: "restore while taken",
+			change: func(ctx context.Context, store *MemoryStore, id string) error {
+				if err := store.DeleteCourse(ctx, "TU/e", id, AnyRevision); err != nil {
+					return err
+				}
+				if _, err := store.AddCourse(ctx, "TU/e", Course{Name: "Calculus", Year: 1, Grade: 8, ECTS: 5}); err != nil {
+					return err
+				}
+				return store.RestoreCourse(ctx, "TU/e", id)
+			},
+			wantErr: true,
+		},
+		{
+			name: "move a retake into the same year",
+			change: func(ctx context.Context, store *MemoryStore, id string) error {
+				retake, err := store.AddCourse(ctx, "TU/e", Course{Name: "Calculus", Year: 2, Grade: 8, ECTS: 5})
+				if err != nil {
+					return err
+				}
+				return store.UpdateCourse(ctx, "TU/e", retake, AnyRevision, map[string]string{"Year": "1"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "update keeping name and year",
+			change: func(ctx context.Context, store *MemoryStore, id string) error {
+				return store.UpdateCourse(ctx, "TU/e", id, AnyRevision, map[string]string{"Name": "Calculus", "Grade": "9"})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStore()
+			id, err := store.AddCourse(ctx, "TU/e", Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5})
+			if err != nil {
+				t.Fatalf("AddCourse() = %v", err)
+			}
+
+			err = tt.change(ctx, store, id)
+			if tt.wantErr {
+				if !errors.Is(err, ErrValidation) {
+					t.Errorf("error = %v, want ErrValidation", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+		})
+	}
+}
+
+// TestImportCoursesSkipsTakenNames checks that importing without replace skips an active
+// course whose name and year are taken, but keeps retakes and trashed courses.
+func TestImportCoursesSkipsTakenNames(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	if _, err := store.AddCourse(ctx, "TU/e", Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5}); err != nil {
+		t.Fatalf("AddCourse() = %v", err)
+	}
+
+	deleted := time.Now()
+	imported, err := store.ImportCourses(ctx, "TU/e", []Course{
+		{Name: "Calculus", Year: 1, Grade: 6, ECTS: 5},
+		{Name: "Calculus", Year: 2, Grade: 8, ECTS: 5},
+		{Name: "Calculus", Year: 1, Grade: 5, ECTS: 5, DeletedAt: &deleted},
+	}, false)
+	if err != nil {
+		t.Fatalf("ImportCourses() = %v", err)
+	}
+	if imported != 2 {
+		t.Errorf("ImportCourses() imported %d course(s), want 2", imported)
+	}
+}
@@ -0,0 +1,366 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"bytes"         // Compacting the archived data for the checksum
+	"context"       // Cancellation of operations
+	"crypto/sha256" // Integrity checksum of the archived data
+	"encoding/hex"  // Hex encoding of the checksum
+	"encoding/json" // Archive encoding
+	"errors"        // Error inspection
+	"fmt"           // Formatted I/O package
+	"io"            // Archive readers and writers
+	"slices"        // Reversing the history into chronological order
+	"strings"       // Building the restore report
+	"time"          // Creation time of the archive
+
+	// Internal packages
+	"UniGrades/internal/university" // Known universities
+)
+
+// BackupFormat identifies a UniGrades backup archive.
+const BackupFormat = "unigrades-backup"
+
+// BackupVersion is the version of the archive layout written by this build.
+// Archives with a newer version are rejected instead of being restored partially.
+const BackupVersion = 1
+
+// checksumPrefix names the hash algorithm of Backup.Checksum.
+const checksumPrefix = "sha256:"
+
+// Backup is a self-describing archive of every university's courses and history.
+// On disk it is a JSON document; the checksum covers the compact JSON encoding of Data,
+// so the file may be reformatted, but any change to its contents is detected.
+type Backup struct {
+	// Format is always BackupFormat
+	Format string `json:"format"`
+	// Version is the archive layout version (BackupVersion when written by this build)
+	Version int `json:"version"`
+	// SchemaVersion is the course schema version of the archived courses
+	SchemaVersion int `json:"schemaVersion"`
+	// CreatedAt is when the backup was taken
+	CreatedAt time.Time `json:"createdAt"`
+	// Checksum is the SHA-256 hash of the archived data, e.g. "sha256:9f86d0..."
+	Checksum string `json:"checksum"`
+	// Data holds the archived settings, courses and history
+	Data BackupData `json:"data"`
+}
+
+// BackupData is the checksummed content of a Backup.
+type BackupData struct {
+	// Settings are the command-line options the backup was taken with, by flag name
+	Settings map[string]string `json:"settings"`
+	// Universities holds the courses and history of every university
+	Universities []UniversityBackup `json:"universities"`
+	// EncryptionSalt is the salt the store derived passphrase keys with, if it keeps one,
+	// so grades sealed with a passphrase can be decrypted from the archive alone
+	EncryptionSalt []byte `json:"encryptionSalt,omitempty"`
+}
+
+// UniversityBackup holds the archived courses and history of one university.
+type UniversityBackup struct {
+	// Name is the display name of the university (e.g. "TU/e")
+	Name string `json:"name"`
+	// Courses are the active and trashed courses, with their IDs, revisions and deletion times
+	Courses []Course `json:"courses"`
+	// History is the change history of the university, oldest first
+	History []HistoryEntry `json:"history"`
+}
+
+// CourseImporter is implemented by stores that can take back courses from a backup
+// exactly as they were archived, including IDs, revisions and deletion times.
+type CourseImporter interface {
+	// ImportCourses writes the courses of the university. With replace, every stored
+	// course of the university (active or trashed) is removed first; otherwise courses
+	// whose ID is already taken, or whose name an active course has in the same year,
+	// are skipped.
+	// Returns the number of courses written.
+	ImportCourses(ctx context.Context, uni string, courses []Course, replace bool) (int, error)
+}
+
+// HistoryImporter is implemented by history stores that can take back archived entries.
+type HistoryImporter interface {
+	// ImportHistory appends the entries of the university, oldest first. With replace,
+	// the stored history of the university is removed first; otherwise entries whose
+	// ID is already stored are skipped. Returns the number of entries written.
+	ImportHistory(ctx context.Context, uni string, entries []HistoryEntry, replace bool) (int, error)
+}
+
+// CreateBackup collects the courses and history of every known university.
+// Malformed documents can't be archived; they are left out and reported through a
+// *MalformedError, returned together with the backup like GetAllCourses does.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the reads
+//	store: The store to read the courses from
+//	history: The history store to read the change history from
+//	settings: The command-line options to record in the archive
+//
+// Returns:
+//
+//	The backup, and a *MalformedError if documents were skipped or another error if a read failed.
+func CreateBackup(ctx context.Context, store CourseStore, history HistoryStore, settings map[string]string) (Backup, error) {
+	b := Backup{
+		Format:        BackupFormat,
+		Version:       BackupVersion,
+		SchemaVersion: SchemaVersion(),
+		CreatedAt:     time.Now().UTC(),
+		Data:          BackupData{Settings: settings, Universities: []UniversityBackup{}},
+	}
+	if b.Data.Settings == nil {
+		b.Data.Settings = map[string]string{}
+	}
+	if keeper, ok := FindStore[SaltKeeper](store); ok {
+		salt, err := keeper.EncryptionSalt(ctx)
+		if err != nil {
+			return Backup{}, fmt.Errorf("failed to back up the encryption salt: %w", err)
+		}
+		b.Data.EncryptionSalt = salt
+	}
+
+	var malformed []MalformedDocument
+	for _, uni := range university.Names() {
+		ub := UniversityBackup{Name: uni, Courses: []Course{}, History: []HistoryEntry{}}
+		for _, load := range []func(context.Context, string) ([]Course, error){store.GetAllCourses, store.GetTrashedCourses} {
+			courses, err := load(ctx, uni)
+			var m *MalformedError
+			if errors.As(err, &m) {
+				malformed = append(malformed, m.Documents...)
+			} else if err != nil {
+				return Backup{}, fmt.Errorf("failed to back up the courses of %s: %w", uni, err)
+			}
+			ub.Courses = append(ub.Courses, courses...)
+		}
+
+		entries, err := history.GetHistory(ctx, uni)
+		if err != nil {
+			return Backup{}, fmt.Errorf("failed to back up the history of %s: %w", uni, err)
+		}
+		slices.Reverse(entries)
+		ub.History = append(ub.History, entries...)
+
+		b.Data.Universities = append(b.Data.Universities, ub)
+	}
+
+	if len(malformed) > 0 {
+		return b, &MalformedError{Documents: malformed}
+	}
+	return b, nil
+}
+
+// backupChecksum returns the checksum of compact JSON-encoded archive data.
+func backupChecksum(data []byte) (string, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(compact.Bytes())
+	return checksumPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+// rawBackup is a Backup whose data is kept undecoded, so the checksum can be computed
+// over exactly the bytes that were read.
+type rawBackup struct {
+	Format        string          `json:"format"`
+	Version       int             `json:"version"`
+	SchemaVersion int             `json:"schemaVersion"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	Checksum      string          `json:"checksum"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// WriteBackup writes the backup as indented JSON, computing its checksum.
+//
+// Parameters:
+//
+//	w: Where the archive is written
+//	b: The backup to write; its Checksum is ignored and recomputed
+//
+// Returns:
+//
+//	An error if the backup cannot be encoded or written.
+func WriteBackup(w io.Writer, b Backup) error {
+	data, err := json.Marshal(b.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode backup: %w", err)
+	}
+	checksum, err := backupChecksum(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode backup: %w", err)
+	}
+
+	raw := rawBackup{
+		Format:        b.Format,
+		Version:       b.Version,
+		SchemaVersion: b.SchemaVersion,
+		CreatedAt:     b.CreatedAt,
+		Checksum:      checksum,
+		Data:          data,
+	}
+	out, err := json.MarshalIndent(raw, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup: %w", err)
+	}
+	if _, err := w.Write(append(out, '\n')); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+// ReadBackup reads an archive written by WriteBackup and verifies it.
+//
+// Parameters:
+//
+//	r: The archive to read
+//
+// Returns:
+//
+//	The backup, or an error wrapping ErrValidation if the archive is not a UniGrades backup,
+//	was written by a newer release, or doesn't match its checksum.
+func ReadBackup(r io.Reader) (Backup, error) {
+	var raw rawBackup
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return Backup{}, fmt.Errorf("%w: not a backup archive: %w", ErrValidation, err)
+	}
+	if raw.Format != BackupFormat {
+		return Backup{}, fmt.Errorf("%w: not a backup archive (format %q)", ErrValidation, raw.Format)
+	}
+	if raw.Version > BackupVersion || raw.SchemaVersion > SchemaVersion() {
+		return Backup{}, fmt.Errorf("%w: the backup was written by a newer release (archive version %d, schema version %d)", ErrValidation, raw.Version, raw.SchemaVersion)
+	}
+
+	checksum, err := backupChecksum(raw.Data)
+	if err != nil || checksum != raw.Checksum {
+		return Backup{}, fmt.Errorf("%w: the backup is corrupt: its checksum doesn't match its contents", ErrValidation)
+	}
+
+	b := Backup{
+		Format:        raw.Format,
+		Version:       raw.Version,
+		SchemaVersion: raw.SchemaVersion,
+		CreatedAt:     raw.CreatedAt,
+		Checksum:      raw.Checksum,
+	}
+	if err := json.Unmarshal(raw.Data, &b.Data); err != nil {
+		return Backup{}, fmt.Errorf("%w: the backup is corrupt: %w", ErrValidation, err)
+	}
+	return b, nil
+}
+
+// RestoreResult reports what a restore wrote for one university.
+type RestoreResult struct {
+	// University is the restored university
+	University string
+	// Courses is the number of courses written
+	Courses int
+	// Skipped is the number of archived courses left out because they were already stored
+	Skipped int
+	// History is the number of history entries written
+	History int
+}
+
+// RestoreReport describes the outcome of RestoreBackup.
+type RestoreReport struct {
+	// Replace tells whether the stored data was replaced rather than merged
+	Replace bool
+	// Results lists the restored universities, in archive order
+	Results []RestoreResult
+}
+
+// String renders the report, e.g.
+// "Merged the backup: TU/e: 12 course(s) restored, 3 skipped, 40 history entries".
+func (r RestoreReport) String() string {
+	var b strings.Builder
+	if r.Replace {
+		b.WriteString("Replaced all data with the backup:")
+	} else {
+		b.WriteString("Merged the backup:")
+	}
+	for _, res := range r.Results {
+		fmt.Fprintf(&b, "\n  %s: %d course(s) restored", res.University, res.Courses)
+		if res.Skipped > 0 {
+			fmt.Fprintf(&b, ", %d already stored and skipped", res.Skipped)
+		}
+		fmt.Fprintf(&b, ", %d history entries", res.History)
+	}
+	return b.String()
+}
+
+// RestoreBackup writes the courses and history of a backup into the stores.
+// Every archived course is validated before anything is written. In merge mode, courses
+// and history entries that are already stored are kept as they are; in replace mode,
+// each university in the archive is emptied first.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the writes
+//	b: The backup to restore, as returned by ReadBackup
+//	store: The store the courses are written to
+//	history: The history store the entries are written to
+//	replace: Whether to replace the stored data instead of merging into it
+//
+// Returns:
+//
+//	A report of what was written, or an error wrapping ErrValidation if the backup holds
+//	an invalid course, or encrypted grades and the store isn't wrapped with WithEncryption.
+//	A failed write stops the restore; the report covers what was written until then.
+func RestoreBackup(ctx context.Context, b Backup, store CourseImporter, history HistoryImporter, replace bool) (RestoreReport, error) {
+	report := RestoreReport{Replace: replace}
+
+	// Without the key, sealed grades would be restored as a grade of 0
+	if sealed := sealedGrades(b); sealed > 0 {
+		var encrypted bool
+		if courses, ok := store.(CourseStore); ok {
+			_, encrypted = FindStore[*encryptedStore](courses)
+		}
+		if !encrypted {
+			return report, fmt.Errorf("%w: the backup holds %d encrypted grade(s); restore it with the keyfile or passphrase it was taken with", ErrValidation, sealed)
+		}
+	}
+
+	for _, ub := range b.Data.Universities {
+		for _, c := range ub.Courses {
+			if err := validateCourse(c); err != nil {
+				return report, fmt.Errorf("course '%s' of %s: %w", c.Name, ub.Name, err)
+			}
+		}
+	}
+
+	for _, ub := range b.Data.Universities {
+		res := RestoreResult{University: ub.Name}
+		written, err := store.ImportCourses(ctx, ub.Name, ub.Courses, replace)
+		if err != nil {
+			return report, fmt.Errorf("failed to restore the courses of %s: %w", ub.Name, err)
+		}
+		res.Courses, res.Skipped = written, len(ub.Courses)-written
+
+		res.History, err = history.ImportHistory(ctx, ub.Name, ub.History, replace)
+		if err != nil {
+			return report, fmt.Errorf("failed to restore the history of %s: %w", ub.Name, err)
+		}
+		report.Results = append(report.Results, res)
+	}
+	return report, nil
+}
+
+// sealedGrades counts the encrypted grades of a backup, in its courses and history.
+func sealedGrades(b Backup) int {
+	sealed := 0
+	for _, ub := range b.Data.Universities {
+		for _, c := range ub.Courses {
+			if c.SealedGrade != "" {
+				sealed++
+			}
+		}
+		for _, e := range ub.History {
+			for _, snapshot := range []*Course{e.Before, e.After} {
+				if snapshot != nil && snapshot.SealedGrade != "" {
+					sealed++
+				}
+			}
+		}
+	}
+	return sealed
+}
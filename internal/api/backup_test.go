@@ -0,0 +1,75 @@
+package api
+
+import (
+	// Standard library imports
+	"bytes"   // Archive buffer
+	"context" // Contexts of the store calls
+	"errors"  // Error inspection
+	"strings" // Tampering with the archive text
+	"testing" // Test framework
+)
+
+// TestReadBackupVerifiesArchive writes an archive, changes it in various ways and checks
+// which changes ReadBackup rejects. Changes to the layout only, like indentation, are accepted.
+func TestReadBackupVerifiesArchive(t *testing.T) {
+	const uni = "TU/e"
+	ctx := context.Background()
+
+	store := NewMemoryStore()
+	if _, err := store.AddCourse(ctx, uni, Course{Name: "Calculus", Year: 1, Grade: 7.25, ECTS: 5}); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := CreateBackup(ctx, store, NewMemoryHistory(), map[string]string{"store": "memory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteBackup(&buf, backup); err != nil {
+		t.Fatal(err)
+	}
+	archive := buf.String()
+
+	tests := []struct {
+		name   string
+		tamper func(archive string) string
+		// wantErr is true if ReadBackup must reject the archive with ErrValidation
+		wantErr bool
+	}{
+		{"unchanged", func(a string) string { return a }, false},
+		{"reindented", func(a string) string { return strings.ReplaceAll(a, "    ", "\t") }, false},
+		{"grade changed", func(a string) string { return strings.Replace(a, "7.25", "9.25", 1) }, true},
+		{"setting changed", func(a string) string { return strings.Replace(a, `"memory"`, `"file"`, 1) }, true},
+		{"checksum changed", func(a string) string { return strings.Replace(a, checksumPrefix, checksumPrefix+"0", 1) }, true},
+		{"checksum removed", func(a string) string { return strings.Replace(a, `"checksum"`, `"removed"`, 1) }, true},
+		{"other format", func(a string) string { return strings.Replace(a, BackupFormat, "other-backup", 1) }, true},
+		{"newer version", func(a string) string { return strings.Replace(a, `"version": 1`, `"version": 99`, 1) }, true},
+		{"truncated", func(a string) string { return a[:len(a)/2] }, true},
+		{"not JSON", func(a string) string { return "course,grade\nCalculus,7.25\n" }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := tt.tamper(archive)
+			if tt.name != "unchanged" && tampered == archive {
+				t.Fatal("the archive was not changed")
+			}
+
+			got, err := ReadBackup(strings.NewReader(tampered))
+			if tt.wantErr {
+				if !errors.Is(err, ErrValidation) {
+					t.Errorf("ReadBackup() error = %v, want ErrValidation", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadBackup() = %v", err)
+			}
+			var courses []Course
+			for _, u := range got.Data.Universities {
+				courses = append(courses, u.Courses...)
+			}
+			if len(courses) != 1 || courses[0].Grade != 7.25 || got.Data.Settings["store"] != "memory" {
+				t.Errorf("read courses %v and settings %v, want the archived ones", courses, got.Data.Settings)
+			}
+		})
+	}
+}
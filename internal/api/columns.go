@@ -0,0 +1,212 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"cmp"          // Ordering of exported courses
+	"encoding/csv" // CSV exports
+	"fmt"          // Formatted I/O package
+	"io"           // Export writers
+	"math"         // Rounding of computed values
+	"slices"       // Sorting of exported courses
+	"strconv"      // Number formatting
+	"strings"      // Parsing column lists
+	"time"         // Deletion times
+)
+
+// ColumnType is the kind of value a column holds; it decides how the value is formatted.
+type ColumnType int
+
+const (
+	// TextColumn holds strings, shown as they are
+	TextColumn ColumnType = iota
+	// IntegerColumn holds whole numbers
+	IntegerColumn
+	// DecimalColumn holds numbers shown with at most two decimals, e.g. "7.5" or "37.33"
+	DecimalColumn
+	// TimeColumn holds points in time, shown in local time, e.g. "2024-05-01 14:30"
+	TimeColumn
+)
+
+// Alignment is the horizontal alignment of a column's values.
+type Alignment int
+
+const (
+	// AlignLeft aligns values to the left, used for text
+	AlignLeft Alignment = iota
+	// AlignRight aligns values to the right, so numbers line up
+	AlignRight
+)
+
+// Column describes a column of the course table: its label, the type and alignment of its
+// values, and whether it is shown unless the user picks the columns. Every table and export
+// of courses renders from the column registry, so a course missing a value shows an empty cell
+// instead of whatever its stored document happens to hold.
+type Column struct {
+	// Key identifies the column on the command line, e.g. "Points"
+	Key string
+	// Label is the column header, e.g. "Grade Points"
+	Label string
+	// Type is the kind of value the column holds
+	Type ColumnType
+	// Align is the alignment of the column's values
+	Align Alignment
+	// Visible tells whether the column is shown by default
+	Visible bool
+	// value extracts the column's value from a course; nil means no value
+	value func(Course) interface{}
+}
+
+// columns is the column registry, in display order. The ID column always leads the
+// course table, so courses sharing a name can be told apart and targeted by commands.
+var columns = []Column{
+	{Key: "ID", Label: "ID", Type: TextColumn, Align: AlignLeft, Visible: true,
+		value: func(c Course) interface{} { return c.ShortID() }},
+	{Key: "Name", Label: "Name", Type: TextColumn, Align: AlignLeft, Visible: true,
+		value: func(c Course) interface{} { return c.Name }},
+	{Key: "Year", Label: "Year", Type: IntegerColumn, Align: AlignRight, Visible: true,
+		value: func(c Course) interface{} { return c.Year }},
+	{Key: "Grade", Label: "Grade", Type: DecimalColumn, Align: AlignRight, Visible: true,
+		value: func(c Course) interface{} { return c.Grade }},
+	{Key: "ECTS", Label: "ECTS", Type: DecimalColumn, Align: AlignRight, Visible: true,
+		value: func(c Course) interface{} { return c.ECTS }},
+	// Grade points weigh the grade by the credits earned with it; their sum divided by the
+	// total ECTS is the weighted average
+	{Key: "Points", Label: "Grade Points", Type: DecimalColumn, Align: AlignRight, Visible: false,
+		value: func(c Course) interface{} { return c.Grade * c.ECTS }},
+	{Key: "Deleted", Label: "Deleted", Type: TimeColumn, Align: AlignLeft, Visible: false,
+		value: func(c Course) interface{} {
+			if c.DeletedAt == nil {
+				return nil
+			}
+			return *c.DeletedAt
+		}},
+}
+
+// Columns returns every registered column, in display order.
+func Columns() []Column {
+	return append([]Column(nil), columns...)
+}
+
+// DefaultColumns returns the columns shown by default, in display order.
+func DefaultColumns() []Column {
+	var visible []Column
+	for _, col := range columns {
+		if col.Visible {
+			visible = append(visible, col)
+		}
+	}
+	return visible
+}
+
+// LookupColumn returns the registered column with the given key, ignoring case.
+func LookupColumn(key string) (Column, bool) {
+	for _, col := range columns {
+		if strings.EqualFold(col.Key, key) {
+			return col, true
+		}
+	}
+	return Column{}, false
+}
+
+// ParseColumns picks the columns named in a comma-separated list of keys, e.g. "Name,Grade,Points".
+// The ID column is always included first. An empty list selects the default columns.
+//
+// Parameters:
+//
+//	list: The comma-separated column keys, in the order they should be shown
+//
+// Returns:
+//
+//	The selected columns, or an error wrapping ErrValidation naming an unknown column.
+func ParseColumns(list string) ([]Column, error) {
+	if strings.TrimSpace(list) == "" {
+		return DefaultColumns(), nil
+	}
+
+	selected := []Column{columns[0]}
+	for _, key := range strings.Split(list, ",") {
+		col, ok := LookupColumn(strings.TrimSpace(key))
+		if !ok {
+			keys := make([]string, len(columns))
+			for i, c := range columns {
+				keys[i] = c.Key
+			}
+			return nil, fmt.Errorf("%w: unknown column %q. Valid columns are: %s", ErrValidation, strings.TrimSpace(key), strings.Join(keys, ", "))
+		}
+		if col.Key != "ID" {
+			selected = append(selected, col)
+		}
+	}
+	return selected, nil
+}
+
+// Value returns the column's value for the course, or nil if the course has none.
+func (col Column) Value(c Course) interface{} {
+	return col.value(c)
+}
+
+// Format returns the column's value for the course as it is displayed, or an empty
+// string if the course has no value in this column.
+func (col Column) Format(c Course) string {
+	v := col.Value(c)
+	if v == nil {
+		return ""
+	}
+	switch col.Type {
+	case IntegerColumn:
+		return strconv.Itoa(v.(int))
+	case DecimalColumn:
+		return strconv.FormatFloat(math.Round(v.(float64)*100)/100, 'f', -1, 64)
+	case TimeColumn:
+		return v.(time.Time).Local().Format("2006-01-02 15:04")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Labels returns the headers of the columns.
+func Labels(cols []Column) []string {
+	labels := make([]string, len(cols))
+	for i, col := range cols {
+		labels[i] = col.Label
+	}
+	return labels
+}
+
+// FormatRow returns the formatted values of a course in the given columns.
+func FormatRow(cols []Column, c Course) []string {
+	row := make([]string, len(cols))
+	for i, col := range cols {
+		row[i] = col.Format(c)
+	}
+	return row
+}
+
+// WriteCSV exports courses as CSV, rendered from the column registry like the course table:
+// a header row of column labels, then the active courses ordered by year, with every value
+// formatted as the table shows it. Trashed courses are left out.
+//
+// Parameters:
+//
+//	w: The writer the CSV is written to
+//	cols: The columns to export, e.g. the result of ParseColumns
+//	courses: The courses to export
+//
+// Returns:
+//
+//	An error if writing failed.
+func WriteCSV(w io.Writer, cols []Column, courses []Course) error {
+	active := ActiveCourses(courses)
+	slices.SortStableFunc(active, func(a, b Course) int {
+		return cmp.Compare(a.Year, b.Year)
+	})
+
+	cw := csv.NewWriter(w)
+	cw.Write(Labels(cols))
+	for _, c := range active {
+		cw.Write(FormatRow(cols, c))
+	}
+	cw.Flush()
+	return cw.Error()
+}
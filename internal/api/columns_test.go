@@ -0,0 +1,37 @@
+package api
+
+import (
+	// Standard library imports
+	"strings" // Building the expected CSV
+	"testing" // Test framework
+	"time"    // Deletion time of a trashed course
+)
+
+// TestWriteCSV checks that an export renders the chosen columns of the registry like the
+// table does: labels as header, formatted values, courses by year, and no trashed courses.
+func TestWriteCSV(t *testing.T) {
+	deleted := time.Now()
+	courses := []Course{
+		{Name: "Databases", Year: 2, Grade: 7.256, ECTS: 5},
+		{Name: "Calculus, Part I", Year: 1, Grade: 8, ECTS: 2.5},
+		{Name: "Dropped", Year: 1, Grade: 6, ECTS: 5, DeletedAt: &deleted},
+	}
+	cols, err := ParseColumns("Name,Grade,Points")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := WriteCSV(&b, cols, courses); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"ID,Name,Grade,Grade Points",
+		courses[1].ShortID() + `,"Calculus, Part I",8,20`,
+		courses[0].ShortID() + ",Databases,7.26,36.28",
+		"",
+	}, "\n")
+	if b.String() != want {
+		t.Errorf("WriteCSV() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
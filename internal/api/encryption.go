@@ -0,0 +1,548 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context"         // Cancellation of operations
+	"crypto/aes"      // AES block cipher
+	"crypto/cipher"   // GCM authenticated encryption
+	"crypto/pbkdf2"   // Key derivation from passphrases
+	"crypto/rand"     // Random nonces
+	"crypto/sha256"   // Key derivation from keyfiles
+	"encoding/base64" // Text encoding of sealed values
+	"errors"          // Error inspection
+	"fmt"             // Formatted I/O package
+	"os"              // Reading keyfiles
+	"strconv"         // Grade encoding
+	"strings"         // Sealed value prefix
+	"time"            // Trash purge times
+
+	// Third-party packages
+	"go.mongodb.org/mongo-driver/v2/bson" // ObjectIDs of sealed courses
+)
+
+// sealedPrefix marks the format of a sealed value: AES-256-GCM, base64 nonce and ciphertext.
+const sealedPrefix = "v1:"
+
+// Parameters of the PBKDF2 derivation of keys from passphrases.
+const (
+	// passphraseIterations is the number of PBKDF2 iterations
+	passphraseIterations = 600000
+	// saltSize is the length of the random salt, in bytes
+	saltSize = 16
+)
+
+// SaltKeeper is implemented by stores that keep the salt of passphrase-derived keys with
+// their data, so every machine using the same data derives the same key from the same passphrase.
+type SaltKeeper interface {
+	// EncryptionSalt returns the stored salt, or nil if none was stored yet.
+	EncryptionSalt(ctx context.Context) ([]byte, error)
+	// SetEncryptionSalt stores the salt. Unless replace is set, a salt that is already
+	// stored is kept. Returns the salt stored afterwards.
+	SetEncryptionSalt(ctx context.Context, salt []byte, replace bool) ([]byte, error)
+}
+
+// PassphraseSalt returns the salt to derive a passphrase key with: the one kept by the
+// store or, the first time a passphrase is used, a random one that is stored first.
+func PassphraseSalt(ctx context.Context, keeper SaltKeeper) ([]byte, error) {
+	return keeper.SetEncryptionSalt(ctx, newSalt(), false)
+}
+
+// CachedPassphraseSalt is PassphraseSalt for a store with an offline cache at cachePath.
+// The salt is copied into the cache file, so a passphrase can still be used while the
+// store is unreachable; the copy is returned then.
+func CachedPassphraseSalt(ctx context.Context, keeper SaltKeeper, cachePath string) ([]byte, error) {
+	cache, err := NewFileStore(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	salt, err := PassphraseSalt(ctx, keeper)
+	if err == nil {
+		_, err = cache.SetEncryptionSalt(ctx, salt, true)
+		return salt, err
+	}
+	if !errors.Is(err, ErrUnavailable) && !errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if cached, _ := cache.EncryptionSalt(ctx); cached != nil {
+		return cached, nil
+	}
+	return nil, err
+}
+
+// newSalt returns a random salt for a passphrase-derived key.
+func newSalt() []byte {
+	salt := make([]byte, saltSize)
+	rand.Read(salt)
+	return salt
+}
+
+// GradeCipher encrypts grades on the client before they are stored, so the database only
+// ever holds ciphertext for them. Each sealed value is bound to its course ID, so it can't
+// be copied onto another course unnoticed.
+type GradeCipher struct {
+	// aead encrypts and authenticates sealed values
+	aead cipher.AEAD
+	// salt is the salt the key was derived with, nil for keyfile keys
+	salt []byte
+}
+
+// NewPassphraseCipher derives the encryption key from a passphrase and a salt with PBKDF2-SHA256.
+// The salt is the one kept by the store, see SaltKeeper.
+// Returns an error if the passphrase is empty or the salt is too short.
+func NewPassphraseCipher(passphrase string, salt []byte) (*GradeCipher, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("%w: the passphrase must not be empty", ErrValidation)
+	}
+	if len(salt) < saltSize {
+		return nil, fmt.Errorf("%w: the salt must have at least %d bytes", ErrValidation, saltSize)
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, passphraseIterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive the encryption key: %w", err)
+	}
+	c, err := newGradeCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	c.salt = salt
+	return c, nil
+}
+
+// NewKeyfileCipher derives the encryption key from the contents of a keyfile
+// (e.g. 32 random bytes from /dev/urandom). Returns an error if the file can't be read
+// or holds fewer than 16 bytes.
+func NewKeyfileCipher(path string) (*GradeCipher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyfile: %w", err)
+	}
+	if len(data) < 16 {
+		return nil, fmt.Errorf("%w: the keyfile %s is too short, use at least 16 random bytes", ErrValidation, path)
+	}
+	key := sha256.Sum256(data)
+	return newGradeCipher(key[:])
+}
+
+// newGradeCipher creates an AES-256-GCM cipher from a 32-byte key.
+func newGradeCipher(key []byte) (*GradeCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &GradeCipher{aead: aead}, nil
+}
+
+// sealGrade encrypts the grade of the course with the given ID, e.g. "v1:3q2+7w...".
+func (c *GradeCipher) sealGrade(id bson.ObjectID, grade float64) string {
+	nonce := make([]byte, c.aead.NonceSize())
+	rand.Read(nonce)
+	plain := strconv.FormatFloat(grade, 'g', -1, 64)
+	sealed := c.aead.Seal(nonce, nonce, []byte(plain), []byte(id.Hex()+"/Grade"))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed)
+}
+
+// openGrade decrypts a grade sealed by sealGrade for the course with the given ID.
+// Returns an error if the value was sealed with another key or for another course.
+func (c *GradeCipher) openGrade(id bson.ObjectID, sealed string) (float64, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	if !strings.HasPrefix(sealed, sealedPrefix) || err != nil || len(data) < c.aead.NonceSize() {
+		return 0, errors.New("the sealed grade is not in a known format")
+	}
+	nonce, ciphertext := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, []byte(id.Hex()+"/Grade"))
+	if err != nil {
+		return 0, errors.New("the grade can't be decrypted with this key")
+	}
+	return strconv.ParseFloat(string(plain), 64)
+}
+
+// Salt returns the salt the key was derived from a passphrase with, or nil for a keyfile key.
+func (c *GradeCipher) Salt() []byte {
+	return c.salt
+}
+
+// seal returns the course as it is stored: the grade sealed and Grade left at 0.
+func (c *GradeCipher) seal(course Course) Course {
+	course.SealedGrade = c.sealGrade(course.ID, course.Grade)
+	course.Grade = 0
+	return course
+}
+
+// open returns the course with its sealed grade decrypted. Courses stored before
+// encryption was turned on have no sealed grade and are returned unchanged.
+func (c *GradeCipher) open(course Course) (Course, error) {
+	if course.SealedGrade == "" {
+		return course, nil
+	}
+	grade, err := c.openGrade(course.ID, course.SealedGrade)
+	if err != nil {
+		return course, err
+	}
+	course.Grade = grade
+	course.SealedGrade = ""
+	return course, nil
+}
+
+// openAll decrypts the courses that can be decrypted. The others are left out and
+// reported through a *MalformedError, merged with one the store may have returned.
+func (c *GradeCipher) openAll(courses []Course, err error) ([]Course, error) {
+	var malformed *MalformedError
+	if err != nil && !errors.As(err, &malformed) {
+		return nil, err
+	}
+
+	opened := make([]Course, 0, len(courses))
+	var skipped []MalformedDocument
+	if malformed != nil {
+		skipped = append(skipped, malformed.Documents...)
+	}
+	for _, course := range courses {
+		o, err := c.open(course)
+		if err != nil {
+			skipped = append(skipped, MalformedDocument{ID: course.ID.Hex(), Reason: err.Error()})
+			continue
+		}
+		opened = append(opened, o)
+	}
+	if len(skipped) > 0 {
+		return opened, &MalformedError{Documents: skipped}
+	}
+	return opened, nil
+}
+
+// openConflict decrypts the current course carried by a *ConflictError.
+func (c *GradeCipher) openConflict(err error) error {
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		if opened, openErr := c.open(conflict.Current); openErr == nil {
+			return &ConflictError{Current: opened, Expected: conflict.Expected}
+		}
+	}
+	return err
+}
+
+// SealStoredGrades encrypts the plaintext grades of courses stored before encryption was
+// turned on, using the same document rewriting as the schema migrations. Courses whose
+// grade is already sealed are left alone, so running it again changes nothing.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the rewrite
+//	store: The store whose documents are rewritten
+//	c: The cipher to seal the grades with
+//
+// Returns:
+//
+//	The number of sealed courses, or an error if the documents can't be rewritten.
+func SealStoredGrades(ctx context.Context, store Migratable, c *GradeCipher) (int, error) {
+	return store.RewriteDocuments(ctx, func(uni string, doc Document) (bool, error) {
+		if sealed, _ := doc["SealedGrade"].(string); sealed != "" {
+			return false, nil
+		}
+		id, ok := documentID(doc)
+		if !ok {
+			return false, nil
+		}
+		var grade float64
+		switch v := doc["Grade"].(type) {
+		case float64:
+			grade = v
+		case int32:
+			grade = float64(v)
+		case int64:
+			grade = float64(v)
+		default:
+			return false, nil
+		}
+		doc["SealedGrade"] = c.sealGrade(id, grade)
+		doc["Grade"] = float64(0)
+		return true, nil
+	}, false)
+}
+
+// documentID returns the ObjectID of a raw course document: "_id" in MongoDB, "id" in the data file.
+func documentID(doc Document) (bson.ObjectID, bool) {
+	switch v := doc["_id"].(type) {
+	case bson.ObjectID:
+		return v, true
+	}
+	if hex, ok := doc["id"].(string); ok {
+		id, err := bson.ObjectIDFromHex(hex)
+		return id, err == nil
+	}
+	return bson.ObjectID{}, false
+}
+
+// storedUpdater is implemented by the stores and decorators that can sit below WithEncryption.
+// Their updateStored is UpdateCourse that also accepts the sealed grade. It is unexported,
+// so neither the HTTP API nor other callers of UpdateCourse can write ciphertext.
+type storedUpdater interface {
+	// updateStored updates fields of the course like UpdateCourse, including SealedGrade
+	updateStored(ctx context.Context, uni, id string, revision int64, updates map[string]string) error
+}
+
+// updateStored writes updates that may include the sealed grade to the store.
+// Returns an error wrapping ErrValidation if the store can't hold sealed grades.
+func updateStored(ctx context.Context, store CourseStore, uni, id string, revision int64, updates map[string]string) error {
+	s, ok := store.(storedUpdater)
+	if !ok {
+		return fmt.Errorf("%w: the store can't hold encrypted grades", ErrValidation)
+	}
+	return s.updateStored(ctx, uni, id, revision, updates)
+}
+
+// writeUpdates writes the updates through UpdateCourse or, if stored is set, through updateStored.
+func writeUpdates(ctx context.Context, store CourseStore, uni, id string, revision int64, updates map[string]string, stored bool) error {
+	if stored {
+		return updateStored(ctx, store, uni, id, revision, updates)
+	}
+	return store.UpdateCourse(ctx, uni, id, revision, updates)
+}
+
+// encryptedStore is a CourseStore decorator that seals grades before they reach the
+// wrapped store and opens them when they are read back.
+type encryptedStore struct {
+	// store is the wrapped store, which only sees sealed grades
+	store CourseStore
+	// cipher seals and opens the grades
+	cipher *GradeCipher
+}
+
+// WithEncryption wraps a store so that grades are encrypted on the client: AddCourse and
+// UpdateCourse store the grade sealed in SealedGrade with Grade left at 0, and every read
+// decrypts it again. Courses that can't be decrypted (e.g. with another key) are skipped
+// and reported through a *MalformedError. Statistics are computed from the decrypted
+// courses, since the database can't aggregate ciphertext. It belongs around every other
+// decorator, the offline cache included, so nothing below it writes plaintext grades to disk.
+// The grade is the only encrypted field: courses have no notes, and their name, year and
+// ECTS stay readable so the database can validate, index and sort them.
+//
+// Parameters:
+//
+//	store: The store to wrap
+//	c: The cipher holding the key
+//
+// Returns:
+//
+//	A CourseStore that encrypts grades.
+func WithEncryption(store CourseStore, c *GradeCipher) CourseStore {
+	return &encryptedStore{store: store, cipher: c}
+}
+
+// Unwrap returns the wrapped store.
+func (s *encryptedStore) Unwrap() CourseStore {
+	return s.store
+}
+
+// GetAllCourses returns the active courses with their grades decrypted.
+func (s *encryptedStore) GetAllCourses(ctx context.Context, uni string) ([]Course, error) {
+	return s.cipher.openAll(s.store.GetAllCourses(ctx, uni))
+}
+
+// GetCourse returns the course with its grade decrypted.
+func (s *encryptedStore) GetCourse(ctx context.Context, uni, id string) (Course, error) {
+	course, err := s.store.GetCourse(ctx, uni, id)
+	if err != nil {
+		return course, err
+	}
+	opened, err := s.cipher.open(course)
+	if err != nil {
+		return Course{}, &MalformedError{Documents: []MalformedDocument{{ID: id, Reason: err.Error()}}}
+	}
+	return opened, nil
+}
+
+// GetStats computes the statistics from the decrypted courses.
+func (s *encryptedStore) GetStats(ctx context.Context, uni string) (CourseStats, error) {
+	courses, err := s.GetAllCourses(ctx, uni)
+	if err != nil && !IsWarning(err) {
+		return CourseStats{}, err
+	}
+	return ComputeStats(uni, courses), err
+}
+
+// AddCourse validates the course and stores it with its grade sealed. The course is
+// assigned its ID here, since the sealed grade is bound to it.
+func (s *encryptedStore) AddCourse(ctx context.Context, uni string, course Course) (string, error) {
+	if err := validateCourse(course); err != nil {
+		return "", err
+	}
+	if course.ID.IsZero() {
+		course.ID = bson.NewObjectID()
+	}
+	return s.store.AddCourse(ctx, uni, s.cipher.seal(course))
+}
+
+// DeleteCourse delegates to the wrapped store.
+func (s *encryptedStore) DeleteCourse(ctx context.Context, uni, id string, revision int64) error {
+	return s.cipher.openConflict(s.store.DeleteCourse(ctx, uni, id, revision))
+}
+
+// GetTrashedCourses returns the trashed courses with their grades decrypted.
+func (s *encryptedStore) GetTrashedCourses(ctx context.Context, uni string) ([]Course, error) {
+	return s.cipher.openAll(s.store.GetTrashedCourses(ctx, uni))
+}
+
+// RestoreCourse delegates to the wrapped store.
+func (s *encryptedStore) RestoreCourse(ctx context.Context, uni, id string) error {
+	return s.store.RestoreCourse(ctx, uni, id)
+}
+
+// PurgeTrash delegates to the wrapped store.
+func (s *encryptedStore) PurgeTrash(ctx context.Context, uni string, before time.Time) (int, error) {
+	return s.store.PurgeTrash(ctx, uni, before)
+}
+
+// UpdateCourse validates the updates and, if the grade changes, stores it sealed.
+func (s *encryptedStore) UpdateCourse(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	value, ok := updates["Grade"]
+	if !ok {
+		return s.cipher.openConflict(s.store.UpdateCourse(ctx, uni, id, revision, updates))
+	}
+
+	// Check the plaintext values here; the wrapped store only sees the sealed grade
+	if _, err := parseUpdates(updates); err != nil {
+		return err
+	}
+	oid, err := ParseID(id)
+	if err != nil {
+		return err
+	}
+	grade, _ := strconv.ParseFloat(value, 64)
+
+	sealed := make(map[string]string, len(updates)+1)
+	for field, v := range updates {
+		sealed[field] = v
+	}
+	sealed["Grade"] = "0"
+	sealed["SealedGrade"] = s.cipher.sealGrade(oid, grade)
+	return s.cipher.openConflict(updateStored(ctx, s.store, uni, id, revision, sealed))
+}
+
+// ImportCourses writes the courses of a backup to the wrapped store, sealing the grades
+// of courses archived before encryption was turned on. Sealed grades are written as archived.
+// Returns an error wrapping ErrValidation if the wrapped store can't restore backups.
+func (s *encryptedStore) ImportCourses(ctx context.Context, uni string, courses []Course, replace bool) (int, error) {
+	importer, ok := s.store.(CourseImporter)
+	if !ok {
+		return 0, fmt.Errorf("%w: the store can't restore backups", ErrValidation)
+	}
+	sealed := make([]Course, len(courses))
+	for i, c := range courses {
+		if c.SealedGrade == "" {
+			if c.ID.IsZero() {
+				c.ID = bson.NewObjectID()
+			}
+			c = s.cipher.seal(c)
+		}
+		sealed[i] = c
+	}
+	return importer.ImportCourses(ctx, uni, sealed, replace)
+}
+
+// encryptedHistory is a HistoryStore decorator that seals the grades of the snapshots
+// in history entries, so the change log doesn't leak the grades either.
+type encryptedHistory struct {
+	// history is the wrapped history, which only sees sealed grades
+	history HistoryStore
+	// cipher seals and opens the grades
+	cipher *GradeCipher
+}
+
+// WithHistoryEncryption wraps a history store so that the grades in the before and after
+// snapshots are stored sealed, like WithEncryption does for the courses themselves.
+// Snapshots that can't be decrypted are returned with their grade at 0.
+func WithHistoryEncryption(history HistoryStore, c *GradeCipher) HistoryStore {
+	return &encryptedHistory{history: history, cipher: c}
+}
+
+// RecordHistory records the entry with the grades of its snapshots sealed.
+func (h *encryptedHistory) RecordHistory(ctx context.Context, entry HistoryEntry) error {
+	return h.history.RecordHistory(ctx, h.seal(entry))
+}
+
+// ImportHistory writes the entries of a backup to the wrapped history, sealing the grades
+// of snapshots archived before encryption was turned on.
+// Returns an error wrapping ErrValidation if the wrapped history can't restore backups.
+func (h *encryptedHistory) ImportHistory(ctx context.Context, uni string, entries []HistoryEntry, replace bool) (int, error) {
+	importer, ok := h.history.(HistoryImporter)
+	if !ok {
+		return 0, fmt.Errorf("%w: the history store can't restore backups", ErrValidation)
+	}
+	sealed := make([]HistoryEntry, len(entries))
+	for i, e := range entries {
+		sealed[i] = h.seal(e)
+	}
+	return importer.ImportHistory(ctx, uni, sealed, replace)
+}
+
+// seal returns the entry with the grades of its snapshots sealed, leaving snapshots
+// that are sealed already as they are.
+func (h *encryptedHistory) seal(entry HistoryEntry) HistoryEntry {
+	for _, snapshot := range []**Course{&entry.Before, &entry.After} {
+		if *snapshot != nil && (*snapshot).SealedGrade == "" {
+			sealed := h.cipher.seal(**snapshot)
+			*snapshot = &sealed
+		}
+	}
+	return entry
+}
+
+// OpenBackup decrypts the sealed grades of an archive with the cipher they were sealed
+// with, e.g. one derived from the salt recorded in the archive. Restoring it through
+// WithEncryption then seals them again with the key of the store it is restored into.
+// Returns an error if a sealed grade can't be decrypted with the cipher.
+func OpenBackup(b Backup, c *GradeCipher) (Backup, error) {
+	universities := make([]UniversityBackup, len(b.Data.Universities))
+	for i, u := range b.Data.Universities {
+		courses := make([]Course, len(u.Courses))
+		for j, course := range u.Courses {
+			opened, err := c.open(course)
+			if err != nil {
+				return Backup{}, fmt.Errorf("failed to decrypt the grade of course %s of %s: %w", course.ID.Hex(), u.Name, err)
+			}
+			courses[j] = opened
+		}
+		entries := make([]HistoryEntry, len(u.History))
+		for j, e := range u.History {
+			for _, snapshot := range []**Course{&e.Before, &e.After} {
+				if *snapshot == nil {
+					continue
+				}
+				opened, err := c.open(**snapshot)
+				if err != nil {
+					return Backup{}, fmt.Errorf("failed to decrypt a grade in the history of %s: %w", u.Name, err)
+				}
+				*snapshot = &opened
+			}
+			entries[j] = e
+		}
+		u.Courses, u.History = courses, entries
+		universities[i] = u
+	}
+	b.Data.Universities = universities
+	return b, nil
+}
+
+// GetHistory returns the entries with the grades of their snapshots decrypted.
+func (h *encryptedHistory) GetHistory(ctx context.Context, uni string) ([]HistoryEntry, error) {
+	entries, err := h.history.GetHistory(ctx, uni)
+	for i := range entries {
+		for _, snapshot := range []**Course{&entries[i].Before, &entries[i].After} {
+			if *snapshot == nil {
+				continue
+			}
+			opened, openErr := h.cipher.open(**snapshot)
+			if openErr != nil {
+				opened.SealedGrade = ""
+			}
+			*snapshot = &opened
+		}
+	}
+	return entries, err
+}
@@ -0,0 +1,428 @@
+package api
+
+import (
+	// Standard library imports
+	"bytes"           // Comparison of salts
+	"context"         // Contexts of the store calls
+	"encoding/base64" // Tampering with sealed grades
+	"errors"          // Error inspection
+	"fmt"             // Names of subtests
+	"os"              // Reading the offline cache files
+	"path/filepath"   // Data file location
+	"strings"         // Searching the offline cache files and sealed grades
+	"testing"         // Test framework
+
+	// Third-party packages
+	"go.mongodb.org/mongo-driver/v2/bson" // Course IDs
+)
+
+// testCipher returns a cipher with a fixed key, so tests don't pay for key derivation.
+func testCipher(t *testing.T) *GradeCipher {
+	t.Helper()
+	c, err := newGradeCipher([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// TestGradeCipherRoundTrip seals grades and checks that they only open again with the
+// same key, for the same course, and unchanged.
+func TestGradeCipherRoundTrip(t *testing.T) {
+	c := testCipher(t)
+	other, err := newGradeCipher([]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, otherID := bson.NewObjectID(), bson.NewObjectID()
+
+	for _, grade := range []float64{0, 5.5, 7.25, 8.125, 10} {
+		sealed := c.sealGrade(id, grade)
+		if !strings.HasPrefix(sealed, sealedPrefix) {
+			t.Fatalf("sealed grade %q lacks the %q prefix", sealed, sealedPrefix)
+		}
+		if again := c.sealGrade(id, grade); again == sealed {
+			t.Errorf("sealing %v twice gave the same ciphertext", grade)
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data[len(data)-1] ^= 1
+		changed := sealedPrefix + base64.StdEncoding.EncodeToString(data)
+
+		tests := []struct {
+			name   string
+			cipher *GradeCipher
+			id     bson.ObjectID
+			sealed string
+			// wantErr is true if the sealed grade must not open
+			wantErr bool
+		}{
+			{"same course", c, id, sealed, false},
+			{"other course", c, otherID, sealed, true},
+			{"other key", other, id, sealed, true},
+			{"changed ciphertext", c, id, changed, true},
+			{"unknown version", c, id, "v2:" + strings.TrimPrefix(sealed, sealedPrefix), true},
+			{"not base64", c, id, sealedPrefix + "not base64!", true},
+			{"too short", c, id, sealedPrefix + "AAAA", true},
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%v %s", grade, tt.name), func(t *testing.T) {
+				got, err := tt.cipher.openGrade(tt.id, tt.sealed)
+				if tt.wantErr {
+					if err == nil {
+						t.Errorf("openGrade() = %v, want an error", got)
+					}
+					return
+				}
+				if err != nil || got != grade {
+					t.Errorf("openGrade() = %v, %v; want %v", got, err, grade)
+				}
+			})
+		}
+	}
+
+	// A sealed grade copied onto another course doesn't open, so it is reported as malformed
+	course := c.seal(Course{ID: id, Name: "Calculus", Year: 1, Grade: 7.5, ECTS: 5})
+	copied := course
+	copied.ID = otherID
+	opened, err := c.openAll([]Course{course, copied}, nil)
+	var malformed *MalformedError
+	if !errors.As(err, &malformed) || len(malformed.Documents) != 1 || malformed.Documents[0].ID != otherID.Hex() {
+		t.Errorf("openAll() error = %v, want the copied course reported as malformed", err)
+	}
+	if len(opened) != 1 || opened[0].Grade != 7.5 || opened[0].SealedGrade != "" {
+		t.Errorf("openAll() = %v, want only the original course with grade 7.5", opened)
+	}
+}
+
+// TestSealedGradeOnlyThroughEncryption checks that UpdateCourse rejects the sealed grade
+// on every store, while WithEncryption still stores grades sealed on top of each of them.
+func TestSealedGradeOnlyThroughEncryption(t *testing.T) {
+	const uni = "TU/e"
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		open func(t *testing.T) CourseStore
+	}{
+		{"memory", func(t *testing.T) CourseStore { return NewMemoryStore() }},
+		{"file", func(t *testing.T) CourseStore {
+			s, err := NewFileStore(filepath.Join(t.TempDir(), "courses.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		}},
+		{"history and timeouts", func(t *testing.T) CourseStore {
+			return WithTimeouts(WithHistory(NewMemoryStore(), NewMemoryHistory()), DefaultTimeouts())
+		}},
+		{"offline", func(t *testing.T) CourseStore {
+			s, err := NewOfflineStore(NewMemoryStore(), filepath.Join(t.TempDir(), "offline-cache.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := tt.open(t)
+			store := WithEncryption(base, testCipher(t))
+			id, err := store.AddCourse(ctx, uni, Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for name, s := range map[string]CourseStore{"base": base, "encrypted": store} {
+				err := s.UpdateCourse(ctx, uni, id, AnyRevision, map[string]string{"SealedGrade": "v1:forged"})
+				if !errors.Is(err, ErrValidation) {
+					t.Errorf("%s UpdateCourse with SealedGrade: error = %v, want ErrValidation", name, err)
+				}
+			}
+
+			if err := store.UpdateCourse(ctx, uni, id, AnyRevision, map[string]string{"Grade": "8.5"}); err != nil {
+				t.Fatalf("encrypted grade update: %v", err)
+			}
+			stored, err := base.GetCourse(ctx, uni, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Grade != 0 || stored.SealedGrade == "" {
+				t.Errorf("stored grade = %v, sealed %q; want 0 and a sealed grade", stored.Grade, stored.SealedGrade)
+			}
+			opened, err := store.GetCourse(ctx, uni, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if opened.Grade != 8.5 {
+				t.Errorf("decrypted grade = %v, want 8.5", opened.Grade)
+			}
+		})
+	}
+}
+
+// TestOfflineCacheHoldsSealedGrades edits a course while the server is down and checks that
+// neither the offline cache nor its outbox holds the grades in plain text, and that the
+// sealed grade is replayed once the server is back.
+func TestOfflineCacheHoldsSealedGrades(t *testing.T) {
+	const uni = "TU/e"
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	remote := &flakyStore{MemoryStore: NewMemoryStore()}
+	offline, err := NewOfflineStore(WithHistory(remote, NewMemoryHistory()), filepath.Join(dir, "offline-cache.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := WithEncryption(offline, testCipher(t))
+	id, err := store.AddCourse(ctx, uni, Course{Name: "Calculus", Year: 1, Grade: 7.25, ECTS: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetAllCourses(ctx, uni); err != nil {
+		t.Fatal(err)
+	}
+
+	remote.down = true
+	if err := store.UpdateCourse(ctx, uni, id, AnyRevision, map[string]string{"Grade": "9.75"}); err != nil {
+		t.Fatalf("offline edit: %v", err)
+	}
+	for _, name := range []string{"offline-cache.json", "offline-cache.outbox.json"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "7.25") || strings.Contains(string(data), "9.75") {
+			t.Errorf("%s holds a plaintext grade:\n%s", name, data)
+		}
+	}
+
+	remote.down = false
+	if err := offline.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if conflicts := offline.Status().Conflicts; len(conflicts) > 0 {
+		t.Fatalf("conflicts = %v, want none", conflicts)
+	}
+	course, err := WithEncryption(remote, testCipher(t)).GetCourse(ctx, uni, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if course.Grade != 9.75 {
+		t.Errorf("grade on the server = %v, want 9.75", course.Grade)
+	}
+}
+
+// TestRestoreSealedWithoutKey restores a backup with encrypted grades into a store without
+// a cipher and checks that it is refused instead of storing grades of 0.
+func TestRestoreSealedWithoutKey(t *testing.T) {
+	const uni = "TU/e"
+	ctx := context.Background()
+
+	c := testCipher(t)
+	sealed := NewMemoryStore()
+	if _, err := WithEncryption(sealed, c).AddCourse(ctx, uni, Course{Name: "Calculus", Year: 1, Grade: 7.5, ECTS: 5}); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := CreateBackup(ctx, sealed, NewMemoryHistory(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := NewMemoryStore()
+	_, err = RestoreBackup(ctx, backup, base, NewMemoryHistory(), false)
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("RestoreBackup() error = %v, want ErrValidation", err)
+	}
+	if courses, _ := base.GetAllCourses(ctx, uni); len(courses) != 0 {
+		t.Errorf("restored %d course(s) without the key, want none", len(courses))
+	}
+
+	// With the key, the same archive restores
+	if _, err := RestoreBackup(ctx, backup, WithEncryption(base, c).(CourseImporter), NewMemoryHistory(), false); err != nil {
+		t.Fatalf("RestoreBackup() with the key = %v", err)
+	}
+}
+
+// TestRestoreSealsPlaintextGrades restores a backup taken before encryption was turned on
+// and checks that its grades are stored sealed, in the courses and in the history.
+func TestRestoreSealsPlaintextGrades(t *testing.T) {
+	const uni = "TU/e"
+	ctx := context.Background()
+
+	plain := NewMemoryStore()
+	plainHistory := NewMemoryHistory()
+	if _, err := WithHistory(plain, plainHistory).AddCourse(ctx, uni, Course{Name: "Calculus", Year: 1, Grade: 7.5, ECTS: 5}); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := CreateBackup(ctx, plain, plainHistory, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := testCipher(t)
+	base, history := NewMemoryStore(), NewMemoryHistory()
+	store := WithEncryption(base, c).(CourseImporter)
+	if _, err := RestoreBackup(ctx, backup, store, WithHistoryEncryption(history, c).(HistoryImporter), false); err != nil {
+		t.Fatal(err)
+	}
+
+	courses, err := base.GetAllCourses(ctx, uni)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := history.GetHistory(ctx, uni)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(courses) != 1 || len(entries) != 1 {
+		t.Fatalf("restored %d course(s) and %d history entries, want 1 and 1", len(courses), len(entries))
+	}
+	for _, stored := range []Course{courses[0], *entries[0].After} {
+		if stored.Grade != 0 || stored.SealedGrade == "" {
+			t.Errorf("stored grade = %v, sealed %q; want 0 and a sealed grade", stored.Grade, stored.SealedGrade)
+		}
+		if opened, err := c.open(stored); err != nil || opened.Grade != 7.5 {
+			t.Errorf("decrypted grade = %v (%v), want 7.5", opened.Grade, err)
+		}
+	}
+}
+
+// TestPassphraseSalt checks that the salt is random per store, and that a store keeps
+// returning the salt it was given first, also after the data file is opened again.
+func TestPassphraseSalt(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "courses.json")
+
+	tests := []struct {
+		name string
+		open func(t *testing.T) SaltKeeper
+		// persistent is true if the store keeps the salt when it is opened again
+		persistent bool
+	}{
+		{"memory", func(t *testing.T) SaltKeeper { return NewMemoryStore() }, false},
+		{"file", func(t *testing.T) SaltKeeper {
+			s, err := NewFileStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keeper := tt.open(t)
+			salt, err := PassphraseSalt(ctx, keeper)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(salt) < saltSize {
+				t.Fatalf("salt has %d bytes, want at least %d", len(salt), saltSize)
+			}
+			if other, _ := PassphraseSalt(ctx, NewMemoryStore()); bytes.Equal(other, salt) {
+				t.Errorf("two stores got the same salt %x", salt)
+			}
+
+			keepers := []SaltKeeper{keeper}
+			if tt.persistent {
+				keepers = append(keepers, tt.open(t))
+			}
+			for _, k := range keepers {
+				again, err := PassphraseSalt(ctx, k)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(again, salt) {
+					t.Errorf("salt = %x, want the stored %x", again, salt)
+				}
+			}
+		})
+	}
+}
+
+// TestCachedPassphraseSalt checks that the salt copied into the offline cache is used
+// while the store is unreachable, and that there is no salt to fall back to before.
+func TestCachedPassphraseSalt(t *testing.T) {
+	ctx := context.Background()
+	cachePath := filepath.Join(t.TempDir(), "offline-cache.json")
+	remote := &flakyStore{MemoryStore: NewMemoryStore()}
+
+	remote.down = true
+	if _, err := CachedPassphraseSalt(ctx, remote, cachePath); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("salt without a cached copy: error = %v, want ErrUnavailable", err)
+	}
+
+	remote.down = false
+	salt, err := CachedPassphraseSalt(ctx, remote, cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote.down = true
+	cached, err := CachedPassphraseSalt(ctx, remote, cachePath)
+	if err != nil {
+		t.Fatalf("salt while unreachable: %v", err)
+	}
+	if !bytes.Equal(cached, salt) {
+		t.Errorf("cached salt = %x, want %x", cached, salt)
+	}
+}
+
+// TestRestoreUnderAnotherSalt backs up a store encrypted with a passphrase, restores the
+// archive into a store with another salt, and checks that the same passphrase decrypts it.
+func TestRestoreUnderAnotherSalt(t *testing.T) {
+	const uni, passphrase = "TU/e", "correct horse battery staple"
+	ctx := context.Background()
+
+	cipherFor := func(keeper SaltKeeper) *GradeCipher {
+		salt, err := PassphraseSalt(ctx, keeper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := NewPassphraseCipher(passphrase, salt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	source := NewMemoryStore()
+	sourceCipher := cipherFor(source)
+	if _, err := WithEncryption(source, sourceCipher).AddCourse(ctx, uni, Course{Name: "Calculus", Year: 1, Grade: 7.5, ECTS: 5}); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := CreateBackup(ctx, source, NewMemoryHistory(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backup.Data.EncryptionSalt, sourceCipher.Salt()) {
+		t.Fatalf("archived salt = %x, want %x", backup.Data.EncryptionSalt, sourceCipher.Salt())
+	}
+
+	target := NewMemoryStore()
+	targetCipher := cipherFor(target)
+	archived, err := NewPassphraseCipher(passphrase, backup.Data.EncryptionSalt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenBackup(backup, testCipher(t)); err == nil {
+		t.Error("OpenBackup with another key succeeded")
+	}
+	opened, err := OpenBackup(backup, archived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := WithHistoryEncryption(NewMemoryHistory(), targetCipher).(HistoryImporter)
+	if _, err := RestoreBackup(ctx, opened, WithEncryption(target, targetCipher).(CourseImporter), history, false); err != nil {
+		t.Fatal(err)
+	}
+
+	courses, err := WithEncryption(target, targetCipher).GetAllCourses(ctx, uni)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(courses) != 1 || courses[0].Grade != 7.5 {
+		t.Errorf("restored courses = %v, want Calculus with grade 7.5", courses)
+	}
+}
@@ -0,0 +1,85 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context"  // Context errors
+	"errors"   // Sentinel error creation
+	"fmt"      // Formatted error messages
+	"net/http" // HTTP status codes
+)
+
+// Sentinel errors returned (wrapped) by CourseStore implementations.
+// Callers should test for them with errors.Is.
+var (
+	// ErrNotFound is returned when the requested course does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrValidation is returned when a course or field value is invalid
+	ErrValidation = errors.New("invalid input")
+	// ErrUnavailable is returned when the storage backend cannot be reached or fails
+	ErrUnavailable = errors.New("storage unavailable")
+	// ErrAmbiguous is returned when a course name matches more than one course
+	ErrAmbiguous = errors.New("ambiguous")
+	// ErrConflict is returned when a course was changed since the revision the caller saw
+	ErrConflict = errors.New("changed by someone else")
+	// ErrUnauthorized is returned when an API token is missing, unknown or revoked
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// ConflictError is returned by DeleteCourse and UpdateCourse when the course no longer
+// has the revision the caller saw. It carries the course as it is now, so the caller
+// can show what changed.
+type ConflictError struct {
+	// Current is the course as it is stored now
+	Current Course
+	// Expected is the revision the caller saw
+	Expected int64
+}
+
+// Error describes the conflict, e.g. "course 'Applied_Math' changed by someone else
+// (revision 3, expected 2)".
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("course '%s' %v (revision %d, expected %d)", e.Current.Name, ErrConflict, e.Current.Revision, e.Expected)
+}
+
+// Changes describes how the course differs now from the state the caller saw,
+// e.g. "Grade: 7 → 9". Changes that only touched the trash list no fields.
+func (e *ConflictError) Changes(seen Course) []string {
+	return fieldDifferences(seen, e.Current)
+}
+
+// Unwrap lets errors.Is match a ConflictError against ErrConflict.
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// StatusCode maps an error returned by a CourseStore to the matching HTTP status code.
+//
+// Parameters:
+//
+//	err: The error to classify
+//
+// Returns:
+//
+//	404 for ErrNotFound, 400 for ErrValidation, 401 for ErrUnauthorized, 409 for ErrAmbiguous and ErrConflict, 504 for timeouts,
+//	503 for ErrUnavailable and canceled operations, and 500 for any other error.
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrAmbiguous), errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,122 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context"       // Cancellation of operations
+	"encoding/json" // JSON encoding of the history file
+	"errors"        // Error inspection
+	"fmt"           // Formatted I/O package
+	"io/fs"         // File system error values
+	"os"            // File operations
+	"path/filepath" // Path manipulation
+	"strings"       // String manipulation
+	"sync"          // Mutex serializing writes to the history file
+)
+
+// FileHistory is a HistoryStore that keeps history entries in a JSON file on the local disk.
+// It backs the file course store; like FileStore, the whole file is rewritten atomically
+// after every new entry.
+type FileHistory struct {
+	// mu serializes writes so the file always matches the in-memory state
+	mu sync.Mutex
+	// path is the location of the JSON history file
+	path string
+	// mem holds the loaded entries
+	mem *MemoryHistory
+}
+
+// HistoryFilePath returns the location of the history file kept next to a data file,
+// e.g. "courses.json" -> "courses.history.json".
+func HistoryFilePath(dataPath string) string {
+	return strings.TrimSuffix(dataPath, filepath.Ext(dataPath)) + ".history.json"
+}
+
+// NewFileHistory opens the history file at path and loads its entries.
+// A missing file is treated as an empty history; it is created on the first entry.
+//
+// Parameters:
+//
+//	path: Location of the JSON history file
+//
+// Returns:
+//
+//	The opened FileHistory, or an error if the file exists but cannot be read or parsed.
+func NewFileHistory(path string) (*FileHistory, error) {
+	h := &FileHistory{path: path, mem: NewMemoryHistory()}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, &h.mem.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if h.mem.entries == nil {
+		h.mem.entries = make(map[string][]HistoryEntry)
+	}
+	return h, nil
+}
+
+// RecordHistory appends an entry and saves the history file.
+// If saving fails, the entry is dropped again so memory and file stay in sync.
+func (h *FileHistory) RecordHistory(ctx context.Context, entry HistoryEntry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.mem.RecordHistory(ctx, entry); err != nil {
+		return err
+	}
+
+	h.mem.mu.Lock()
+	defer h.mem.mu.Unlock()
+
+	data, err := json.MarshalIndent(h.mem.entries, "", "    ")
+	if err == nil {
+		err = writeFileAtomic(h.path, data)
+	}
+	if err != nil {
+		entries := h.mem.entries[entry.University]
+		h.mem.entries[entry.University] = entries[:len(entries)-1]
+		return fmt.Errorf("failed to record history: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// ImportHistory appends the entries and saves the history file.
+// See MemoryHistory.ImportHistory. If saving fails, the previous history is kept.
+func (h *FileHistory) ImportHistory(ctx context.Context, uni string, entries []HistoryEntry, replace bool) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.mem.mu.RLock()
+	previous := h.mem.entries[uni]
+	h.mem.mu.RUnlock()
+
+	imported, err := h.mem.ImportHistory(ctx, uni, entries, replace)
+	if err != nil {
+		return 0, err
+	}
+
+	h.mem.mu.Lock()
+	defer h.mem.mu.Unlock()
+
+	data, err := json.MarshalIndent(h.mem.entries, "", "    ")
+	if err == nil {
+		err = writeFileAtomic(h.path, data)
+	}
+	if err != nil {
+		h.mem.entries[uni] = previous
+		return 0, fmt.Errorf("failed to restore history: %w: %w", ErrUnavailable, err)
+	}
+	return imported, nil
+}
+
+// GetHistory returns every entry of the university, newest first.
+func (h *FileHistory) GetHistory(ctx context.Context, uni string) ([]HistoryEntry, error) {
+	return h.mem.GetHistory(ctx, uni)
+}
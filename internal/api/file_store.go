@@ -0,0 +1,455 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"bytes"         // Comparison of salts
+	"context"       // Cancellation of operations
+	"encoding/json" // JSON encoding of the data file
+	"errors"        // Error inspection
+	"fmt"           // Formatted I/O package
+	"io/fs"         // File system error values
+	"os"            // File operations
+	"path/filepath" // Path manipulation
+	"reflect"       // Comparison of replaced courses
+	"slices"        // Snapshots of the courses
+	"sync"          // Mutex serializing writes to the data file
+	"time"          // Purge cutoff
+)
+
+// fileData is the top-level JSON document stored in the local data file.
+type fileData struct {
+	// SchemaVersion is the schema version of the stored courses; 0 in files written
+	// before versions were recorded
+	SchemaVersion int `json:"schemaVersion"`
+	// EncryptionSalt is the salt of passphrase-derived keys, set once grades are encrypted
+	// with a passphrase
+	EncryptionSalt []byte `json:"encryptionSalt,omitempty"`
+	// Universities maps each university name to its courses
+	Universities map[string][]Course `json:"universities"`
+	// Courses is the single course list written by earlier versions, which only
+	// supported TU/e. It is read into legacyUniversity and never written back.
+	Courses []Course `json:"courses,omitempty"`
+}
+
+// legacyUniversity is the university that courses from the old single-list file format belong to.
+const legacyUniversity = "TU/e"
+
+// FileStore is a CourseStore that keeps courses in a JSON file on the local disk.
+// It lets the application run offline, without a MongoDB Atlas account.
+// All data is held in memory and the whole file is rewritten atomically after every change,
+// so a crash while saving leaves either the old or the new file, never a partial one.
+// A change whose file can't be saved is rolled back, so memory never runs ahead of the file.
+type FileStore struct {
+	// mu serializes mutations so the file always matches the in-memory state
+	mu sync.Mutex
+	// path is the location of the JSON data file
+	path string
+	// mem holds the loaded courses and performs the actual operations
+	mem *MemoryStore
+	// version is the schema version of the courses in the data file
+	version int
+	// salt is the salt of passphrase-derived keys, nil until one is needed
+	salt []byte
+}
+
+// DefaultFilePath returns the default location of the local data file,
+// inside the user's configuration directory (e.g. ~/.config/UniGrades/courses.json).
+func DefaultFilePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "UniGrades", "courses.json"), nil
+}
+
+// NewFileStore opens the data file at path and loads its courses.
+// A missing file is treated as an empty store; it is created on the first change.
+//
+// Parameters:
+//
+//	path: Location of the JSON data file
+//
+// Returns:
+//
+//	The opened FileStore, or an error if the file exists but cannot be read or parsed.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, mem: NewMemoryStore(), version: SchemaVersion()}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var doc fileData
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if doc.Universities == nil {
+		doc.Universities = make(map[string][]Course)
+	}
+	if len(doc.Courses) > 0 {
+		doc.Universities[legacyUniversity] = append(doc.Universities[legacyUniversity], doc.Courses...)
+	}
+	for uni, courses := range doc.Universities {
+		s.mem.courses[uni] = courses
+	}
+	s.version = doc.SchemaVersion
+	s.salt = doc.EncryptionSalt
+
+	return s, nil
+}
+
+// GetAllCourses returns every active course of the university.
+func (s *FileStore) GetAllCourses(ctx context.Context, uni string) ([]Course, error) {
+	return s.mem.GetAllCourses(ctx, uni)
+}
+
+// GetCourse returns the active course with the given ID.
+func (s *FileStore) GetCourse(ctx context.Context, uni, id string) (Course, error) {
+	return s.mem.GetCourse(ctx, uni, id)
+}
+
+// AddCourse stores a new course, saves the data file, and returns the course ID in hex format.
+func (s *FileStore) AddCourse(ctx context.Context, uni string, course Course) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.snapshot()
+	id, err := s.mem.AddCourse(ctx, uni, course)
+	if err != nil {
+		return "", err
+	}
+	if err := s.saveOrRollback(snapshot); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// DeleteCourse moves the course with the given ID to the trash and saves the data file.
+func (s *FileStore) DeleteCourse(ctx context.Context, uni, id string, revision int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.snapshot()
+	if err := s.mem.DeleteCourse(ctx, uni, id, revision); err != nil {
+		return err
+	}
+	return s.saveOrRollback(snapshot)
+}
+
+// GetStats computes the statistics of the active courses of the university.
+func (s *FileStore) GetStats(ctx context.Context, uni string) (CourseStats, error) {
+	return s.mem.GetStats(ctx, uni)
+}
+
+// GetTrashedCourses returns every trashed course of the university.
+func (s *FileStore) GetTrashedCourses(ctx context.Context, uni string) ([]Course, error) {
+	return s.mem.GetTrashedCourses(ctx, uni)
+}
+
+// RestoreCourse moves the trashed course with the given ID back and saves the data file.
+func (s *FileStore) RestoreCourse(ctx context.Context, uni, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.snapshot()
+	if err := s.mem.RestoreCourse(ctx, uni, id); err != nil {
+		return err
+	}
+	return s.saveOrRollback(snapshot)
+}
+
+// PurgeTrash permanently removes the courses trashed before the given time and,
+// if any were removed, saves the data file.
+func (s *FileStore) PurgeTrash(ctx context.Context, uni string, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.snapshot()
+	purged, err := s.mem.PurgeTrash(ctx, uni, before)
+	if err != nil || purged == 0 {
+		return purged, err
+	}
+	if err := s.saveOrRollback(snapshot); err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// UpdateCourse sets one or more fields of the course with the given ID and saves the data file.
+func (s *FileStore) UpdateCourse(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.snapshot()
+	if err := s.mem.UpdateCourse(ctx, uni, id, revision, updates); err != nil {
+		return err
+	}
+	return s.saveOrRollback(snapshot)
+}
+
+// updateStored is UpdateCourse for WithEncryption, accepting the sealed grade too.
+func (s *FileStore) updateStored(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.snapshot()
+	if err := s.mem.updateStored(ctx, uni, id, revision, updates); err != nil {
+		return err
+	}
+	return s.saveOrRollback(snapshot)
+}
+
+// ReplaceCourses replaces either the active or the trashed courses of a university,
+// keeping the others, and saves the data file if anything changed. It lets a FileStore
+// serve as a local copy of another store.
+//
+// Parameters:
+//
+//	uni: The university whose courses are replaced
+//	courses: The new courses
+//	trashed: If true, the trashed courses are replaced, otherwise the active ones
+//
+// Returns:
+//
+//	An error wrapping ErrUnavailable if the data file can't be saved.
+func (s *FileStore) ReplaceCourses(uni string, courses []Course, trashed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.snapshot()
+	s.mem.mu.Lock()
+	current := s.mem.courses[uni]
+	replaced := make([]Course, 0, len(current)+len(courses))
+	for _, c := range current {
+		if c.Trashed() != trashed {
+			replaced = append(replaced, c)
+		}
+	}
+	replaced = append(replaced, courses...)
+	changed := !reflect.DeepEqual(current, replaced)
+	if changed {
+		s.mem.courses[uni] = replaced
+	}
+	s.mem.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+	return s.saveOrRollback(snapshot)
+}
+
+// ImportCourses stores the courses exactly as given and saves the data file.
+// See MemoryStore.ImportCourses.
+func (s *FileStore) ImportCourses(ctx context.Context, uni string, courses []Course, replace bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.snapshot()
+	imported, err := s.mem.ImportCourses(ctx, uni, courses, replace)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.saveOrRollback(snapshot); err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
+
+// StoredSchemaVersion returns the schema version recorded in the data file.
+func (s *FileStore) StoredSchemaVersion(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version, nil
+}
+
+// SetStoredSchemaVersion records the schema version and saves the data file.
+func (s *FileStore) SetStoredSchemaVersion(ctx context.Context, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.version == version {
+		return nil
+	}
+	previous := s.version
+	s.version = version
+	if err := s.save(); err != nil {
+		s.version = previous
+		return err
+	}
+	return nil
+}
+
+// EncryptionSalt returns the salt of passphrase-derived keys recorded in the data file, or nil.
+func (s *FileStore) EncryptionSalt(ctx context.Context) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.salt, nil
+}
+
+// SetEncryptionSalt records the salt of passphrase-derived keys and saves the data file.
+// Unless replace is set, a recorded salt is kept. Returns the recorded salt.
+func (s *FileStore) SetEncryptionSalt(ctx context.Context, salt []byte, replace bool) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.salt != nil && (!replace || bytes.Equal(s.salt, salt)) {
+		return s.salt, nil
+	}
+	previous := s.salt
+	s.salt = salt
+	if err := s.save(); err != nil {
+		s.salt = previous
+		return nil, err
+	}
+	return s.salt, nil
+}
+
+// RewriteDocuments passes every course document in the data file, as stored on disk,
+// to fn. Unless dryRun is set, the changed documents are loaded in place of the current
+// courses and the data file is saved.
+func (s *FileStore) RewriteDocuments(ctx context.Context, fn func(uni string, doc Document) (bool, error), dryRun bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w: %w", s.path, ErrUnavailable, err)
+	}
+
+	// Read the raw documents, so migrations see fields the Course type doesn't know
+	var raw struct {
+		Universities map[string][]Document `json:"universities"`
+		Courses      []Document            `json:"courses"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	if raw.Universities == nil {
+		raw.Universities = make(map[string][]Document)
+	}
+	if len(raw.Courses) > 0 {
+		raw.Universities[legacyUniversity] = append(raw.Universities[legacyUniversity], raw.Courses...)
+	}
+
+	changed := 0
+	for uni, docs := range raw.Universities {
+		for _, doc := range docs {
+			if err := ctx.Err(); err != nil {
+				return changed, err
+			}
+			ok, err := fn(uni, doc)
+			if err != nil {
+				return changed, fmt.Errorf("document %v: %w", doc["id"], err)
+			}
+			if ok {
+				changed++
+			}
+		}
+	}
+	if dryRun || changed == 0 {
+		return changed, nil
+	}
+
+	// Decode the migrated documents into courses and save them
+	migrated, err := json.Marshal(raw.Universities)
+	if err != nil {
+		return changed, fmt.Errorf("failed to encode migrated courses: %w", err)
+	}
+	courses := make(map[string][]Course)
+	if err := json.Unmarshal(migrated, &courses); err != nil {
+		return changed, fmt.Errorf("failed to decode migrated courses: %w", err)
+	}
+	snapshot := s.snapshot()
+	s.mem.mu.Lock()
+	s.mem.courses = courses
+	s.mem.mu.Unlock()
+	return changed, s.saveOrRollback(snapshot)
+}
+
+// snapshot copies the course lists of every university, so a change can be rolled back.
+// Courses are values, so copying the lists is enough. The caller must hold s.mu.
+func (s *FileStore) snapshot() map[string][]Course {
+	s.mem.mu.RLock()
+	defer s.mem.mu.RUnlock()
+
+	courses := make(map[string][]Course, len(s.mem.courses))
+	for uni, list := range s.mem.courses {
+		courses[uni] = slices.Clone(list)
+	}
+	return courses
+}
+
+// saveOrRollback writes the current courses to the data file. If that fails, the courses
+// are reset to the snapshot taken before the change, so the failed change is undone.
+// The caller must hold s.mu.
+func (s *FileStore) saveOrRollback(snapshot map[string][]Course) error {
+	err := s.save()
+	if err != nil {
+		s.mem.mu.Lock()
+		s.mem.courses = snapshot
+		s.mem.mu.Unlock()
+	}
+	return err
+}
+
+// save writes the current courses to the data file.
+// The caller must hold s.mu.
+func (s *FileStore) save() error {
+	s.mem.mu.RLock()
+	data, err := json.MarshalIndent(fileData{SchemaVersion: s.version, EncryptionSalt: s.salt, Universities: s.mem.courses}, "", "    ")
+	s.mem.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode courses: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data.
+// The data is written to a temporary file in the same directory, flushed to disk,
+// and then renamed over the target, so readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	// Remove the temporary file if anything below fails (no-op after a successful rename)
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to flush %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	// Flush the directory entry so the rename itself survives a crash (best effort)
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	// Standard library imports
+	"context"       // Contexts of the store calls
+	"errors"        // Error inspection
+	"os"            // Breaking the data file
+	"path/filepath" // Data file location
+	"reflect"       // Comparison of course lists
+	"testing"       // Test framework
+	"time"          // Purge cutoff
+)
+
+// TestFileStoreRollsBackFailedSaves checks that a change whose data file can't be written
+// leaves the courses in memory as they were.
+func TestFileStoreRollsBackFailedSaves(t *testing.T) {
+	const uni = "TU/e"
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		change func(s *FileStore, id string) error
+	}{
+		{"add", func(s *FileStore, id string) error {
+			_, err := s.AddCourse(ctx, uni, Course{Name: "Calculus", Year: 2, Grade: 8, ECTS: 5})
+			return err
+		}},
+		{"update", func(s *FileStore, id string) error {
+			return s.UpdateCourse(ctx, uni, id, AnyRevision, map[string]string{"Grade": "9"})
+		}},
+		{"delete", func(s *FileStore, id string) error {
+			return s.DeleteCourse(ctx, uni, id, AnyRevision)
+		}},
+		{"import", func(s *FileStore, id string) error {
+			_, err := s.ImportCourses(ctx, uni, nil, true)
+			return err
+		}},
+		{"replace", func(s *FileStore, id string) error {
+			return s.ReplaceCourses(uni, nil, false)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "courses.json")
+			s, err := NewFileStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, err := s.AddCourse(ctx, uni, Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5})
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := s.snapshot()
+
+			// A non-empty directory where the data file belongs can't be replaced
+			if err := os.Remove(path); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Join(path, "blocked"), 0o700); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.change(s, id); !errors.Is(err, ErrUnavailable) {
+				t.Fatalf("change error = %v, want ErrUnavailable", err)
+			}
+			if after := s.snapshot(); !reflect.DeepEqual(after, before) {
+				t.Errorf("courses after failed save = %v, want %v", after, before)
+			}
+		})
+	}
+}
+
+// TestFileStoreRollsBackFailedTrashChanges checks restores and purges of the trash,
+// which need a trashed course to start from.
+func TestFileStoreRollsBackFailedTrashChanges(t *testing.T) {
+	const uni = "TU/e"
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		change func(s *FileStore, id string) error
+	}{
+		{"restore", func(s *FileStore, id string) error {
+			return s.RestoreCourse(ctx, uni, id)
+		}},
+		{"purge", func(s *FileStore, id string) error {
+			_, err := s.PurgeTrash(ctx, uni, time.Now().Add(time.Hour))
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "courses.json")
+			s, err := NewFileStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, err := s.AddCourse(ctx, uni, Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.DeleteCourse(ctx, uni, id, AnyRevision); err != nil {
+				t.Fatal(err)
+			}
+			before := s.snapshot()
+
+			if err := os.Remove(path); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Join(path, "blocked"), 0o700); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.change(s, id); !errors.Is(err, ErrUnavailable) {
+				t.Fatalf("change error = %v, want ErrUnavailable", err)
+			}
+			if after := s.snapshot(); !reflect.DeepEqual(after, before) {
+				t.Errorf("courses after failed save = %v, want %v", after, before)
+			}
+		})
+	}
+}
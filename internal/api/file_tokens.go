@@ -0,0 +1,172 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context"       // Cancellation of operations
+	"encoding/json" // JSON encoding of the tokens file
+	"errors"        // Error inspection
+	"fmt"           // Formatted I/O package
+	"io/fs"         // File system error values
+	"os"            // File operations
+	"path/filepath" // Path manipulation
+	"strings"       // String manipulation
+	"sync"          // Mutex serializing access to the tokens file
+	"time"          // Modification time of the tokens file
+)
+
+// FileTokens is a TokenStore that keeps the API tokens in a JSON file on the local disk.
+// It backs the file and memory course stores; like FileHistory, the whole file is
+// rewritten atomically after every change, readable by the current user only.
+// The file is read again whenever it changed, so tokens created or revoked from the
+// command line take effect on a server that is already running.
+type FileTokens struct {
+	// mu serializes access so the file always matches the in-memory state
+	mu sync.Mutex
+	// path is the location of the JSON tokens file
+	path string
+	// modTime is the modification time of the file when it was last read or written
+	modTime time.Time
+	// mem holds the loaded tokens
+	mem *MemoryTokens
+}
+
+// TokensFilePath returns the location of the tokens file kept next to a data file,
+// e.g. "courses.json" -> "courses.tokens.json".
+func TokensFilePath(dataPath string) string {
+	return strings.TrimSuffix(dataPath, filepath.Ext(dataPath)) + ".tokens.json"
+}
+
+// NewFileTokens opens the tokens file at path and loads its tokens.
+// A missing file is treated as having no tokens; it is created when the first token is created.
+//
+// Parameters:
+//
+//	path: Location of the JSON tokens file
+//
+// Returns:
+//
+//	The opened FileTokens, or an error if the file exists but cannot be read or parsed.
+func NewFileTokens(path string) (*FileTokens, error) {
+	t := &FileTokens{path: path, mem: NewMemoryTokens()}
+	if err := t.reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// reload reads the tokens file again if it changed since it was last read or written.
+func (t *FileTokens) reload() error {
+	info, err := os.Stat(t.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.mem = NewMemoryTokens()
+		t.modTime = time.Time{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", t.path, err)
+	}
+	if info.ModTime().Equal(t.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", t.path, err)
+	}
+	var tokens []APIToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", t.path, err)
+	}
+	mem := NewMemoryTokens()
+	for _, token := range tokens {
+		mem.tokens[token.ID] = token
+	}
+	t.mem = mem
+	t.modTime = info.ModTime()
+	return nil
+}
+
+// save writes every token to the tokens file, oldest first.
+func (t *FileTokens) save(ctx context.Context) error {
+	tokens, err := t.mem.ListTokens(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(tokens, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(t.path, data); err != nil {
+		return err
+	}
+	if info, err := os.Stat(t.path); err == nil {
+		t.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// SaveToken stores a new token and saves the tokens file.
+// If saving fails, the token is dropped again so memory and file stay in sync.
+func (t *FileTokens) SaveToken(ctx context.Context, token APIToken) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.reload(); err != nil {
+		return fmt.Errorf("failed to save token: %w: %w", ErrUnavailable, err)
+	}
+	if err := t.mem.SaveToken(ctx, token); err != nil {
+		return err
+	}
+	if err := t.save(ctx); err != nil {
+		t.mem.RevokeToken(context.Background(), token.ID)
+		return fmt.Errorf("failed to save token: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// ListTokens returns every token, oldest first.
+func (t *FileTokens) ListTokens(ctx context.Context) ([]APIToken, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.reload(); err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w: %w", ErrUnavailable, err)
+	}
+	return t.mem.ListTokens(ctx)
+}
+
+// FindToken returns the token whose secret has the given hash.
+func (t *FileTokens) FindToken(ctx context.Context, hash string) (APIToken, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.reload(); err != nil {
+		return APIToken{}, fmt.Errorf("failed to check token: %w: %w", ErrUnavailable, err)
+	}
+	return t.mem.FindToken(ctx, hash)
+}
+
+// RevokeToken deletes the token with the given ID and saves the tokens file.
+// If saving fails, the token is kept so memory and file stay in sync.
+func (t *FileTokens) RevokeToken(ctx context.Context, id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.reload(); err != nil {
+		return fmt.Errorf("failed to revoke token: %w: %w", ErrUnavailable, err)
+	}
+
+	t.mem.mu.RLock()
+	token := t.mem.tokens[id]
+	t.mem.mu.RUnlock()
+
+	if err := t.mem.RevokeToken(ctx, id); err != nil {
+		return err
+	}
+	if err := t.save(ctx); err != nil {
+		t.mem.SaveToken(context.Background(), token)
+		return fmt.Errorf("failed to revoke token: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
@@ -0,0 +1,403 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context" // Cancellation of operations
+	"fmt"     // Formatted I/O package
+	"strings" // String matching of course references
+	"sync"    // Mutex for concurrent access
+	"time"    // Timestamps of history entries
+
+	// Third-party packages
+	"go.mongodb.org/mongo-driver/v2/bson" // BSON types and ObjectID generation
+)
+
+// HistoryOperation names the kind of course mutation recorded in a HistoryEntry.
+type HistoryOperation string
+
+// Operations recorded in the history.
+const (
+	// OpAdd records a course being added
+	OpAdd HistoryOperation = "add"
+	// OpUpdate records one or more fields of a course being changed
+	OpUpdate HistoryOperation = "update"
+	// OpDelete records a course being deleted (moved to the trash)
+	OpDelete HistoryOperation = "delete"
+	// OpRestore records a trashed course being restored
+	OpRestore HistoryOperation = "restore"
+	// OpPurge records a trashed course being removed permanently
+	OpPurge HistoryOperation = "purge"
+)
+
+// HistoryEntry records a single course mutation with snapshots of the course
+// before and after it. Before is nil for additions and After is nil for deletions and purges.
+type HistoryEntry struct {
+	ID         bson.ObjectID    `bson:"_id,omitempty" json:"id,omitzero"`
+	University string           `bson:"University" json:"university"`
+	CourseID   bson.ObjectID    `bson:"CourseID" json:"courseId"`
+	Operation  HistoryOperation `bson:"Operation" json:"operation"`
+	Time       time.Time        `bson:"Time" json:"time"`
+	Before     *Course          `bson:"Before,omitempty" json:"before,omitempty"`
+	After      *Course          `bson:"After,omitempty" json:"after,omitempty"`
+}
+
+// Course returns the snapshot that identifies the changed course:
+// the state after the mutation, or the state before it for deletions.
+func (e HistoryEntry) Course() Course {
+	if e.After != nil {
+		return *e.After
+	}
+	if e.Before != nil {
+		return *e.Before
+	}
+	return Course{ID: e.CourseID}
+}
+
+// Changes describes the fields that differ between the before and after snapshots,
+// e.g. "Grade: 7 → 8.5". Every operation other than an update lists all fields of the course.
+func (e HistoryEntry) Changes() []string {
+	var changes []string
+	for _, field := range courseFields {
+		if e.Operation != OpUpdate || e.Before == nil || e.After == nil {
+			value, _ := e.Course().Value(field)
+			changes = append(changes, fmt.Sprintf("%s: %v", field, value))
+			continue
+		}
+
+		before, _ := e.Before.Value(field)
+		after, _ := e.After.Value(field)
+		if before != after {
+			changes = append(changes, fmt.Sprintf("%s: %v → %v", field, before, after))
+		}
+	}
+	return changes
+}
+
+// HistoryStore keeps the history of course mutations.
+// Entries are scoped to a university display name, like the courses themselves.
+type HistoryStore interface {
+	// RecordHistory appends an entry to the history.
+	RecordHistory(ctx context.Context, entry HistoryEntry) error
+	// GetHistory returns every history entry of the university, newest first.
+	GetHistory(ctx context.Context, uni string) ([]HistoryEntry, error)
+}
+
+// FilterHistory returns the entries about the course the reference points to.
+// The reference may be a full course ID, a unique ID suffix of at least 6 characters,
+// or a name the course had before or after any of its changes. Unlike ResolveCourse,
+// deleted courses are found too, and a name shared by several courses matches all of them.
+//
+// Parameters:
+//
+//	entries: The history entries to search
+//	ref: The course name or ID to look for; an empty reference matches every entry
+//
+// Returns:
+//
+//	The matching entries, in their original order.
+func FilterHistory(entries []HistoryEntry, ref string) []HistoryEntry {
+	if ref == "" {
+		return entries
+	}
+	lower := strings.ToLower(ref)
+
+	var matches []HistoryEntry
+	for _, e := range entries {
+		hex := e.CourseID.Hex()
+		switch {
+		case hex == lower,
+			len(lower) >= minIDSuffixLength && strings.HasSuffix(hex, lower),
+			e.Before != nil && e.Before.Name == ref,
+			e.After != nil && e.After.Name == ref:
+			matches = append(matches, e)
+		}
+	}
+	return matches
+}
+
+// MemoryHistory is a HistoryStore that keeps all entries in memory.
+// It backs the memory course store, so history is lost when the process exits.
+type MemoryHistory struct {
+	// mu guards entries against concurrent access
+	mu sync.RWMutex
+	// entries maps each university name to its entries, oldest first
+	entries map[string][]HistoryEntry
+}
+
+// NewMemoryHistory creates an empty in-memory HistoryStore.
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{entries: make(map[string][]HistoryEntry)}
+}
+
+// RecordHistory appends an entry, assigning it an ID if it has none.
+func (h *MemoryHistory) RecordHistory(ctx context.Context, entry HistoryEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if entry.ID.IsZero() {
+		entry.ID = bson.NewObjectID()
+	}
+	h.entries[entry.University] = append(h.entries[entry.University], entry)
+	return nil
+}
+
+// ImportHistory appends the entries in the given order, replacing the history of the
+// university first if replace is set. Otherwise entries whose ID is already stored are skipped.
+// Returns the number of entries appended.
+func (h *MemoryHistory) ImportHistory(ctx context.Context, uni string, entries []HistoryEntry, replace bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if replace {
+		delete(h.entries, uni)
+	}
+	stored := make(map[bson.ObjectID]bool, len(h.entries[uni]))
+	for _, e := range h.entries[uni] {
+		stored[e.ID] = true
+	}
+
+	imported := 0
+	for _, e := range entries {
+		if stored[e.ID] {
+			continue
+		}
+		if e.ID.IsZero() {
+			e.ID = bson.NewObjectID()
+		}
+		e.University = uni
+		h.entries[uni] = append(h.entries[uni], e)
+		stored[e.ID] = true
+		imported++
+	}
+	return imported, nil
+}
+
+// GetHistory returns a copy of every entry of the university, newest first.
+func (h *MemoryHistory) GetHistory(ctx context.Context, uni string) ([]HistoryEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stored := h.entries[uni]
+	results := make([]HistoryEntry, len(stored))
+	for i, e := range stored {
+		results[len(stored)-1-i] = e
+	}
+	return results, nil
+}
+
+// historyStore is a CourseStore decorator that records every successful mutation
+// of the wrapped store in a HistoryStore.
+type historyStore struct {
+	store   CourseStore
+	history HistoryStore
+}
+
+// WithHistory wraps a store so that every AddCourse, UpdateCourse, DeleteCourse,
+// RestoreCourse and PurgeTrash is recorded in the history with a timestamp and
+// before/after snapshots. The snapshot taken before a change costs one extra read.
+//
+// Parameters:
+//
+//	store: The store to wrap
+//	history: The history the mutations are recorded in
+//
+// Returns:
+//
+//	A CourseStore that records its mutations.
+func WithHistory(store CourseStore, history HistoryStore) CourseStore {
+	return &historyStore{store: store, history: history}
+}
+
+// Unwrap returns the wrapped store.
+func (s *historyStore) Unwrap() CourseStore {
+	return s.store
+}
+
+// GetAllCourses delegates to the wrapped store.
+func (s *historyStore) GetAllCourses(ctx context.Context, uni string) ([]Course, error) {
+	return s.store.GetAllCourses(ctx, uni)
+}
+
+// GetCourse delegates to the wrapped store.
+func (s *historyStore) GetCourse(ctx context.Context, uni, id string) (Course, error) {
+	return s.store.GetCourse(ctx, uni, id)
+}
+
+// GetStats delegates to the wrapped store.
+func (s *historyStore) GetStats(ctx context.Context, uni string) (CourseStats, error) {
+	return s.store.GetStats(ctx, uni)
+}
+
+// AddCourse adds the course and records it with an "add" entry.
+func (s *historyStore) AddCourse(ctx context.Context, uni string, course Course) (string, error) {
+	id, err := s.store.AddCourse(ctx, uni, course)
+	if err != nil {
+		return "", err
+	}
+
+	course.ID, _ = ParseID(id)
+	if course.Revision == 0 {
+		course.Revision = 1
+	}
+	return id, s.record(ctx, uni, OpAdd, course.ID, nil, &course)
+}
+
+// DeleteCourse deletes the course and records its last state with a "delete" entry.
+func (s *historyStore) DeleteCourse(ctx context.Context, uni, id string, revision int64) error {
+	before, err := s.store.GetCourse(ctx, uni, id)
+	if err != nil {
+		return err
+	}
+	if err := s.store.DeleteCourse(ctx, uni, id, pinRevision(revision, before)); err != nil {
+		return err
+	}
+	return s.record(ctx, uni, OpDelete, before.ID, &before, nil)
+}
+
+// GetTrashedCourses delegates to the wrapped store.
+func (s *historyStore) GetTrashedCourses(ctx context.Context, uni string) ([]Course, error) {
+	return s.store.GetTrashedCourses(ctx, uni)
+}
+
+// RestoreCourse restores the course and records it with a "restore" entry.
+func (s *historyStore) RestoreCourse(ctx context.Context, uni, id string) error {
+	before, err := s.trashedCourse(ctx, uni, id)
+	if err != nil {
+		return err
+	}
+	if err := s.store.RestoreCourse(ctx, uni, id); err != nil {
+		return err
+	}
+
+	after := before
+	after.DeletedAt = nil
+	after.Revision++
+	return s.record(ctx, uni, OpRestore, before.ID, &before, &after)
+}
+
+// PurgeTrash purges the trash and records every removed course with a "purge" entry.
+// Trashed documents that can't be read are purged too, without an entry; the warning
+// about them is passed on, so one malformed document doesn't block every purge.
+func (s *historyStore) PurgeTrash(ctx context.Context, uni string, before time.Time) (int, error) {
+	trashed, warning := s.store.GetTrashedCourses(ctx, uni)
+	if warning != nil && !IsWarning(warning) {
+		return 0, warning
+	}
+	purged, err := s.store.PurgeTrash(ctx, uni, before)
+	if err != nil {
+		return purged, err
+	}
+
+	for _, c := range trashed {
+		if c.DeletedAt.Before(before) {
+			if err := s.record(ctx, uni, OpPurge, c.ID, &c, nil); err != nil {
+				return purged, err
+			}
+		}
+	}
+	return purged, warning
+}
+
+// trashedCourse returns the trashed course with the given ID.
+func (s *historyStore) trashedCourse(ctx context.Context, uni, id string) (Course, error) {
+	oid, err := ParseID(id)
+	if err != nil {
+		return Course{}, err
+	}
+	trashed, err := s.store.GetTrashedCourses(ctx, uni)
+	if err != nil {
+		return Course{}, err
+	}
+	for _, c := range trashed {
+		if c.ID == oid {
+			return c, nil
+		}
+	}
+	return Course{}, fmt.Errorf("course '%s' %w in the trash", id, ErrNotFound)
+}
+
+// UpdateCourse updates the course and records both states with an "update" entry.
+func (s *historyStore) UpdateCourse(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	return s.update(ctx, uni, id, revision, updates, false)
+}
+
+// updateStored is UpdateCourse for WithEncryption, accepting the sealed grade too.
+func (s *historyStore) updateStored(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	return s.update(ctx, uni, id, revision, updates, true)
+}
+
+// update updates the course through UpdateCourse, or through updateStored if stored is
+// set, and records both states with an "update" entry.
+func (s *historyStore) update(ctx context.Context, uni, id string, revision int64, updates map[string]string, stored bool) error {
+	before, err := s.store.GetCourse(ctx, uni, id)
+	if err != nil {
+		return err
+	}
+	if err := writeUpdates(ctx, s.store, uni, id, pinRevision(revision, before), updates, stored); err != nil {
+		return err
+	}
+
+	// The update succeeded on the revision that was read, so the values are known to be
+	// valid and before and after are exactly the states it changed between
+	after := before
+	set, _ := parseStoredUpdates(updates)
+	for _, e := range set {
+		after.setField(e.Key, e.Value)
+	}
+	after.Revision++
+	return s.record(ctx, uni, OpUpdate, before.ID, &before, &after)
+}
+
+// pinRevision returns the revision to write against: the one the caller saw or, for
+// AnyRevision, the one of the course as it was read for the history entry. A change made
+// by someone else in between then fails with a *ConflictError instead of being recorded
+// with the wrong "before" state.
+func pinRevision(revision int64, before Course) int64 {
+	if revision == AnyRevision {
+		return before.Revision
+	}
+	return revision
+}
+
+// HistoryWarning is returned by the mutations of a store wrapped with WithHistory when
+// the change was applied but its history entry could not be recorded. Like *MalformedError,
+// it is a warning: the change itself succeeded and must not be retried.
+type HistoryWarning struct {
+	// Cause is the error that stopped recording the history entry
+	Cause error
+}
+
+// Error describes the warning, e.g. "course saved, but failed to record history: storage unavailable".
+func (e *HistoryWarning) Error() string {
+	return fmt.Sprintf("course saved, but failed to record history: %v", e.Cause)
+}
+
+// record appends a history entry for a mutation that has already been applied.
+// A failure is reported as a *HistoryWarning; the mutation itself is not undone.
+func (s *historyStore) record(ctx context.Context, uni string, op HistoryOperation, courseID bson.ObjectID, before, after *Course) error {
+	entry := HistoryEntry{
+		University: uni,
+		CourseID:   courseID,
+		Operation:  op,
+		Time:       time.Now().UTC(),
+		Before:     before,
+		After:      after,
+	}
+	if err := s.history.RecordHistory(ctx, entry); err != nil {
+		return &HistoryWarning{Cause: err}
+	}
+	return nil
+}
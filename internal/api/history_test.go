@@ -0,0 +1,162 @@
+package api
+
+import (
+	// Standard library imports
+	"context" // Contexts of the store calls
+	"errors"  // Error inspection
+	"testing" // Test framework
+	"time"    // Purge cutoff
+)
+
+// racingStore is a MemoryStore in which someone else changes the course right after
+// every read, as if a second client wrote between the read and the write of a change.
+type racingStore struct {
+	*MemoryStore
+	// racing enables the concurrent change
+	racing bool
+}
+
+func (s *racingStore) GetCourse(ctx context.Context, uni, id string) (Course, error) {
+	course, err := s.MemoryStore.GetCourse(ctx, uni, id)
+	if err == nil && s.racing {
+		s.MemoryStore.UpdateCourse(ctx, uni, id, AnyRevision, map[string]string{"ECTS": "6"})
+	}
+	return course, err
+}
+
+// TestHistoryPinsRevision checks that changes without an expected revision are written
+// against the revision read for the history entry, so a concurrent change makes them fail
+// instead of being recorded with the wrong "before" state.
+func TestHistoryPinsRevision(t *testing.T) {
+	const uni = "TU/e"
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		change func(store CourseStore, id string) error
+	}{
+		{"update", func(store CourseStore, id string) error {
+			return store.UpdateCourse(ctx, uni, id, AnyRevision, map[string]string{"Grade": "9"})
+		}},
+		{"delete", func(store CourseStore, id string) error {
+			return store.DeleteCourse(ctx, uni, id, AnyRevision)
+		}},
+	}
+	for _, tt := range tests {
+		for _, racing := range []bool{false, true} {
+			name := tt.name
+			if racing {
+				name += " racing"
+			}
+			t.Run(name, func(t *testing.T) {
+				inner := &racingStore{MemoryStore: NewMemoryStore()}
+				id, err := inner.AddCourse(ctx, uni, Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5})
+				if err != nil {
+					t.Fatal(err)
+				}
+				history := NewMemoryHistory()
+				store := WithHistory(inner, history)
+				inner.racing = racing
+
+				err = tt.change(store, id)
+				var conflict *ConflictError
+				if got := errors.As(err, &conflict); got != racing {
+					t.Fatalf("change error = %v, want a *ConflictError: %v", err, racing)
+				}
+
+				entries, err := history.GetHistory(ctx, uni)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := map[bool]int{false: 1, true: 0}[racing]; len(entries) != want {
+					t.Errorf("history entries = %d, want %d", len(entries), want)
+				}
+			})
+		}
+	}
+}
+
+// brokenHistory is a HistoryStore that can't record anything, e.g. because its
+// collection is unreachable while the courses are not.
+type brokenHistory struct {
+	*MemoryHistory
+}
+
+func (h brokenHistory) RecordHistory(ctx context.Context, entry HistoryEntry) error {
+	return ErrUnavailable
+}
+
+// TestHistoryFailureIsWarning checks that a change whose history entry can't be recorded
+// is reported as a warning, not as a failure, and stays applied.
+func TestHistoryFailureIsWarning(t *testing.T) {
+	const uni = "TU/e"
+	ctx := context.Background()
+	inner := NewMemoryStore()
+	store := WithHistory(inner, brokenHistory{NewMemoryHistory()})
+
+	id, err := store.AddCourse(ctx, uni, Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5})
+	if !IsWarning(err) {
+		t.Fatalf("add error = %v, want a warning", err)
+	}
+	if errors.Is(err, ErrUnavailable) {
+		t.Errorf("add error %v matches ErrUnavailable, so callers would retry an applied change", err)
+	}
+
+	err = store.UpdateCourse(ctx, uni, id, AnyRevision, map[string]string{"Grade": "9"})
+	if !IsWarning(err) {
+		t.Fatalf("update error = %v, want a warning", err)
+	}
+	course, err := inner.GetCourse(ctx, uni, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if course.Grade != 9 {
+		t.Errorf("grade = %v, want the applied 9", course.Grade)
+	}
+}
+
+// malformedTrashStore is a MemoryStore whose trash also holds a document that can't be read.
+type malformedTrashStore struct {
+	*MemoryStore
+}
+
+func (s malformedTrashStore) GetTrashedCourses(ctx context.Context, uni string) ([]Course, error) {
+	trashed, err := s.MemoryStore.GetTrashedCourses(ctx, uni)
+	if err != nil {
+		return nil, err
+	}
+	return trashed, &MalformedError{Documents: []MalformedDocument{{ID: "unknown", Reason: "Grade is a string"}}}
+}
+
+// TestHistoryPurgeWithMalformedTrash checks that a malformed trashed document doesn't block
+// purging the others: they are purged and recorded, and the malformed one is reported as a warning.
+func TestHistoryPurgeWithMalformedTrash(t *testing.T) {
+	const uni = "TU/e"
+	ctx := context.Background()
+	inner := malformedTrashStore{NewMemoryStore()}
+	history := NewMemoryHistory()
+	store := WithHistory(inner, history)
+
+	id, err := inner.AddCourse(ctx, uni, Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := inner.DeleteCourse(ctx, uni, id, AnyRevision); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := store.PurgeTrash(ctx, uni, time.Now().Add(time.Minute))
+	if !IsWarning(err) {
+		t.Fatalf("purge error = %v, want a warning", err)
+	}
+	if purged != 1 {
+		t.Errorf("purged = %d, want 1", purged)
+	}
+	entries, err := history.GetHistory(ctx, uni)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Operation != OpPurge {
+		t.Errorf("history entries = %v, want one purge entry", entries)
+	}
+}
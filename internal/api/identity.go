@@ -0,0 +1,144 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context" // Cancellation of store operations
+	"errors"  // Error inspection
+	"fmt"     // Formatted I/O package
+	"strings" // String manipulation
+
+	// Third-party packages
+	"go.mongodb.org/mongo-driver/v2/bson" // ObjectIDs
+)
+
+// shortIDLength is the number of trailing hex characters shown as a course's short ID.
+// The trailing characters of an ObjectID hold its counter, so they differ between
+// courses added in the same second, unlike the leading timestamp characters.
+const shortIDLength = 8
+
+// minIDSuffixLength is the minimum number of characters accepted as an ID suffix
+// when resolving a course reference.
+const minIDSuffixLength = 6
+
+// ShortID returns the abbreviated ID shown in tables and accepted by commands.
+func (c Course) ShortID() string {
+	hex := c.ID.Hex()
+	return hex[len(hex)-shortIDLength:]
+}
+
+// ParseID converts a hex string into a course ID.
+// Returns an error wrapping ErrValidation if the string is not a valid ObjectID.
+func ParseID(id string) (bson.ObjectID, error) {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return bson.ObjectID{}, fmt.Errorf("%w: '%s' is not a valid course ID", ErrValidation, id)
+	}
+	return oid, nil
+}
+
+// AmbiguousError is returned when a course reference matches more than one course.
+// It matches ErrAmbiguous with errors.Is.
+type AmbiguousError struct {
+	// Ref is the reference the user gave
+	Ref string
+	// Matches lists every course the reference matched
+	Matches []Course
+}
+
+// Error lists the matching courses so the user can pick one by ID.
+func (e *AmbiguousError) Error() string {
+	options := make([]string, 0, len(e.Matches))
+	for _, c := range e.Matches {
+		options = append(options, fmt.Sprintf("%s (Year %d, Grade %v)", c.ShortID(), c.Year, c.Grade))
+	}
+	return fmt.Sprintf("'%s' is %v: matches %s; use the ID instead", e.Ref, ErrAmbiguous, strings.Join(options, ", "))
+}
+
+// Unwrap lets errors.Is match an AmbiguousError against ErrAmbiguous.
+func (e *AmbiguousError) Unwrap() error {
+	return ErrAmbiguous
+}
+
+// ResolveCourse finds the single course a user-supplied reference points to.
+// The reference may be a course name, a full course ID, or the trailing characters
+// of an ID (at least 6, as shown in the table). Names are matched first.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the lookup
+//	store: The store holding the courses
+//	uni: The university the course belongs to
+//	ref: The course name or ID given by the user
+//
+// Returns:
+//
+//	The matching course, an error wrapping ErrNotFound if nothing matches,
+//	or an *AmbiguousError if the reference matches several courses.
+func ResolveCourse(ctx context.Context, store CourseStore, uni, ref string) (Course, error) {
+	// A full ID can be looked up directly
+	if oid, err := bson.ObjectIDFromHex(ref); err == nil {
+		return store.GetCourse(ctx, uni, oid.Hex())
+	}
+
+	// Malformed documents can't be referenced anyway, so warnings don't stop the lookup
+	courses, err := store.GetAllCourses(ctx, uni)
+	if err != nil && !IsWarning(err) {
+		return Course{}, err
+	}
+	return matchCourse(courses, ref, "")
+}
+
+// ResolveTrashedCourse finds the single trashed course a user-supplied reference points to.
+// The reference is matched exactly like in ResolveCourse, but against the courses in the trash.
+//
+// Returns:
+//
+//	The matching trashed course, an error wrapping ErrNotFound if nothing in the trash
+//	matches, or an *AmbiguousError if the reference matches several trashed courses.
+func ResolveTrashedCourse(ctx context.Context, store CourseStore, uni, ref string) (Course, error) {
+	courses, err := store.GetTrashedCourses(ctx, uni)
+	var malformed *MalformedError
+	if err != nil && !errors.As(err, &malformed) {
+		return Course{}, err
+	}
+
+	// A full ID must match exactly
+	if oid, err := bson.ObjectIDFromHex(ref); err == nil {
+		for _, c := range courses {
+			if c.ID == oid {
+				return c, nil
+			}
+		}
+		return Course{}, fmt.Errorf("course '%s' %w in the trash", ref, ErrNotFound)
+	}
+	return matchCourse(courses, ref, " in the trash")
+}
+
+// matchCourse picks the course a name or ID suffix refers to: names are matched first,
+// then ID suffixes of at least minIDSuffixLength characters. The where suffix is added
+// to the not-found message (e.g. " in the trash").
+func matchCourse(courses []Course, ref, where string) (Course, error) {
+	var matches []Course
+	for _, c := range courses {
+		if c.Name == ref {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) == 0 && len(ref) >= minIDSuffixLength {
+		for _, c := range courses {
+			if strings.HasSuffix(c.ID.Hex(), strings.ToLower(ref)) {
+				matches = append(matches, c)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return Course{}, fmt.Errorf("course '%s' %w%s", ref, ErrNotFound, where)
+	case 1:
+		return matches[0], nil
+	default:
+		return Course{}, &AmbiguousError{Ref: ref, Matches: matches}
+	}
+}
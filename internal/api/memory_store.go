@@ -0,0 +1,350 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context" // Cancellation of operations
+	"fmt"     // Formatted I/O package
+	"sync"    // Mutex for concurrent access from the TUI and HTTP handlers
+	"time"    // Deletion timestamps
+
+	// Third-party packages
+	"go.mongodb.org/mongo-driver/v2/bson" // BSON types and ObjectID generation
+)
+
+// MemoryStore is a CourseStore that keeps all courses in memory.
+// It is useful for running the TUI and HTTP server without a live MongoDB cluster.
+// Data is lost when the process exits. Operations never block, so the context is
+// only checked for cancellation before any work is done.
+type MemoryStore struct {
+	// mu guards courses against concurrent access
+	mu sync.RWMutex
+	// courses maps each university name to its courses, in insertion order
+	courses map[string][]Course
+	// salt is the salt of passphrase-derived keys, nil until one is needed
+	salt []byte
+}
+
+// NewMemoryStore creates an empty in-memory CourseStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{courses: make(map[string][]Course)}
+}
+
+// EncryptionSalt returns the salt of passphrase-derived keys, or nil if none was set.
+func (s *MemoryStore) EncryptionSalt(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.salt, nil
+}
+
+// SetEncryptionSalt sets the salt of passphrase-derived keys, keeping one that is already
+// set unless replace is set. Like the courses, it is lost when the process exits.
+func (s *MemoryStore) SetEncryptionSalt(ctx context.Context, salt []byte, replace bool) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.salt == nil || replace {
+		s.salt = salt
+	}
+	return s.salt, nil
+}
+
+// GetAllCourses returns a copy of every active course of the university, in insertion order.
+func (s *MemoryStore) GetAllCourses(ctx context.Context, uni string) ([]Course, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return ActiveCourses(s.courses[uni]), nil
+}
+
+// GetStats computes the statistics of the active courses of the university.
+func (s *MemoryStore) GetStats(ctx context.Context, uni string) (CourseStats, error) {
+	if err := ctx.Err(); err != nil {
+		return CourseStats{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return ComputeStats(uni, s.courses[uni]), nil
+}
+
+// GetTrashedCourses returns a copy of every trashed course of the university, in insertion order.
+func (s *MemoryStore) GetTrashedCourses(ctx context.Context, uni string) ([]Course, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var results []Course
+	for _, c := range s.courses[uni] {
+		if c.Trashed() {
+			results = append(results, c)
+		}
+	}
+	return results, nil
+}
+
+// AddCourse stores a new course and returns its ObjectID in hex format.
+// A new ID is generated unless the course already has one.
+// Invalid courses, IDs already in use and names another active course has in the same year
+// are rejected with an error wrapping ErrValidation, like MongoStore's unique name index does.
+func (s *MemoryStore) AddCourse(ctx context.Context, uni string, course Course) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if err := validateCourse(course); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	course.University = uni
+	if course.Revision == 0 {
+		course.Revision = 1
+	}
+	if course.ID.IsZero() {
+		course.ID = bson.NewObjectID()
+	} else if s.hasID(uni, course.ID) {
+		return "", fmt.Errorf("%w: course ID %s is already in use", ErrValidation, course.ID.Hex())
+	}
+	if s.hasActiveCourse(uni, course.Name, course.Year, course.ID) {
+		return "", fmt.Errorf("%w: a course named '%s' already exists in year %d", ErrValidation, course.Name, course.Year)
+	}
+	s.courses[uni] = append(s.courses[uni], course)
+	return course.ID.Hex(), nil
+}
+
+// ImportCourses stores the courses exactly as given, replacing every course of the
+// university first if replace is set. Otherwise courses whose ID is in use, or whose name
+// is taken by an active course of the same year, are skipped. Returns the number of courses stored.
+func (s *MemoryStore) ImportCourses(ctx context.Context, uni string, courses []Course, replace bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if replace {
+		delete(s.courses, uni)
+	}
+	imported := 0
+	for _, c := range courses {
+		if s.hasID(uni, c.ID) || (!c.Trashed() && s.hasActiveCourse(uni, c.Name, c.Year, c.ID)) {
+			continue
+		}
+		c.University = uni
+		if c.ID.IsZero() {
+			c.ID = bson.NewObjectID()
+		}
+		if c.Revision == 0 {
+			c.Revision = 1
+		}
+		s.courses[uni] = append(s.courses[uni], c)
+		imported++
+	}
+	return imported, nil
+}
+
+// GetCourse returns the course with the given ID.
+// Returns an error wrapping ErrValidation if the ID is invalid, or ErrNotFound if no course has it.
+func (s *MemoryStore) GetCourse(ctx context.Context, uni, id string) (Course, error) {
+	if err := ctx.Err(); err != nil {
+		return Course{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i, err := s.indexByID(uni, id, false)
+	if err != nil {
+		return Course{}, err
+	}
+	return s.courses[uni][i], nil
+}
+
+// DeleteCourse moves the active course with the given ID to the trash.
+// Returns an error wrapping ErrNotFound if no active course with that ID exists,
+// or a *ConflictError if it doesn't have the given revision.
+func (s *MemoryStore) DeleteCourse(ctx context.Context, uni, id string, revision int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.indexByRevision(uni, id, revision)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	s.courses[uni][i].DeletedAt = &now
+	s.courses[uni][i].Revision++
+	return nil
+}
+
+// RestoreCourse moves the trashed course with the given ID back to the active courses.
+// Returns an error wrapping ErrNotFound if no trashed course with that ID exists, or
+// ErrValidation if an active course has the same name and year.
+func (s *MemoryStore) RestoreCourse(ctx context.Context, uni, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.indexByID(uni, id, true)
+	if err != nil {
+		return err
+	}
+	if c := s.courses[uni][i]; s.hasActiveCourse(uni, c.Name, c.Year, c.ID) {
+		return fmt.Errorf("%w: another active course has the name and year of course '%s'", ErrValidation, id)
+	}
+	s.courses[uni][i].DeletedAt = nil
+	s.courses[uni][i].Revision++
+	return nil
+}
+
+// PurgeTrash permanently removes the courses trashed before the given time.
+func (s *MemoryStore) PurgeTrash(ctx context.Context, uni string, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.courses[uni][:0]
+	for _, c := range s.courses[uni] {
+		if c.Trashed() && c.DeletedAt.Before(before) {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	purged := len(s.courses[uni]) - len(kept)
+	s.courses[uni] = kept
+	return purged, nil
+}
+
+// UpdateCourse sets one or more fields of the course with the given ID.
+// The values are validated and converted exactly like MongoStore.UpdateCourse does,
+// and none are applied unless all of them are valid, the new name and year aren't taken by
+// another active course and the course has the given revision.
+func (s *MemoryStore) UpdateCourse(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	set, err := parseUpdates(updates)
+	if err != nil {
+		return err
+	}
+	return s.update(ctx, uni, id, revision, set)
+}
+
+// updateStored is UpdateCourse for WithEncryption, accepting the sealed grade too.
+func (s *MemoryStore) updateStored(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	set, err := parseStoredUpdates(updates)
+	if err != nil {
+		return err
+	}
+	return s.update(ctx, uni, id, revision, set)
+}
+
+// update applies converted values to the course with the given ID and revision.
+func (s *MemoryStore) update(ctx context.Context, uni, id string, revision int64, set bson.D) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.indexByRevision(uni, id, revision)
+	if err != nil {
+		return err
+	}
+
+	// Apply the converted values to a copy, so nothing changes if its name and year are taken
+	updated := s.courses[uni][i]
+	for _, e := range set {
+		updated.setField(e.Key, e.Value)
+	}
+	if s.hasActiveCourse(uni, updated.Name, updated.Year, updated.ID) {
+		return fmt.Errorf("%w: another active course has the same name and year as course '%s'", ErrValidation, id)
+	}
+	updated.Revision++
+	s.courses[uni][i] = updated
+	return nil
+}
+
+// indexByRevision returns the index of the active course with the given ID, provided it
+// has the given revision (any revision for AnyRevision).
+// Returns an error wrapping ErrNotFound if there is no such course, or a *ConflictError
+// if it has another revision. The caller must hold s.mu.
+func (s *MemoryStore) indexByRevision(uni, id string, revision int64) (int, error) {
+	i, err := s.indexByID(uni, id, false)
+	if err != nil {
+		return -1, err
+	}
+	if current := s.courses[uni][i]; revision != AnyRevision && current.Revision != revision {
+		return -1, &ConflictError{Current: current, Expected: revision}
+	}
+	return i, nil
+}
+
+// indexByID returns the index of the course of the university with the given ID
+// that is in the trash (trashed is true) or active (trashed is false).
+// Returns an error wrapping ErrValidation or ErrNotFound if there is no such course.
+// The caller must hold s.mu.
+func (s *MemoryStore) indexByID(uni, id string, trashed bool) (int, error) {
+	oid, err := ParseID(id)
+	if err != nil {
+		return -1, err
+	}
+	for i, c := range s.courses[uni] {
+		if c.ID == oid && c.Trashed() == trashed {
+			return i, nil
+		}
+	}
+	if trashed {
+		return -1, fmt.Errorf("course '%s' %w in the trash", id, ErrNotFound)
+	}
+	return -1, fmt.Errorf("course '%s' %w", id, ErrNotFound)
+}
+
+// hasID reports whether any course of the university, active or trashed, has the given ID.
+// The caller must hold s.mu.
+func (s *MemoryStore) hasID(uni string, id bson.ObjectID) bool {
+	for _, c := range s.courses[uni] {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// hasActiveCourse reports whether an active course of the university other than the one
+// with the given ID has the given name and year. The caller must hold s.mu.
+func (s *MemoryStore) hasActiveCourse(uni, name string, year int, id bson.ObjectID) bool {
+	for _, c := range s.courses[uni] {
+		if !c.Trashed() && c.ID != id && c.Name == name && c.Year == year {
+			return true
+		}
+	}
+	return false
+}
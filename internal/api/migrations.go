@@ -0,0 +1,219 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context" // Cancellation of operations
+	"fmt"     // Formatted I/O package
+	"strings" // String building for reports
+)
+
+// Document is a stored course document in its raw form, as seen by migrations.
+// Field names are the stored ones (e.g. "Name", "ECTS"), and values keep the type
+// they were stored with, so migrations can convert between representations.
+type Document map[string]interface{}
+
+// Migration upgrades the stored course documents by one schema version.
+// Up is called for every document of every university, including trashed ones,
+// and must be idempotent: a document that is already up to date is left unchanged.
+type Migration struct {
+	// Version is the schema version the documents have after the migration
+	Version int
+	// Description says what the migration changes
+	Description string
+	// Up transforms one document in place and reports whether it changed anything
+	Up func(uni string, doc Document) (bool, error)
+}
+
+// migrations lists every schema migration in the order they must run.
+// New migrations are appended with the next version number; existing ones never change.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "Backfill the University field",
+		Up:          backfillUniversity,
+	},
+	{
+		Version:     2,
+		Description: "Store ECTS as a decimal number",
+		Up:          ectsToDecimal,
+	},
+	{
+		Version:     3,
+		Description: "Number course revisions",
+		Up:          backfillRevision,
+	},
+}
+
+// SchemaVersion returns the schema version of course documents written by this build.
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Migratable is implemented by stores whose stored documents outlive the process and
+// may therefore have been written with an older schema.
+type Migratable interface {
+	// StoredSchemaVersion returns the schema version of the stored documents,
+	// or 0 if no version was ever recorded.
+	StoredSchemaVersion(ctx context.Context) (int, error)
+	// RewriteDocuments passes every stored course document of every university to fn
+	// and, unless dryRun is set, saves the documents fn changed.
+	// Returns the number of changed documents.
+	RewriteDocuments(ctx context.Context, fn func(uni string, doc Document) (bool, error), dryRun bool) (int, error)
+	// SetStoredSchemaVersion records the schema version of the stored documents.
+	SetStoredSchemaVersion(ctx context.Context, version int) error
+}
+
+// MigrationResult reports how many documents a single migration changed.
+type MigrationResult struct {
+	// Migration is the migration that ran
+	Migration Migration
+	// Changed is the number of documents the migration changed (or would change in a dry run)
+	Changed int
+}
+
+// MigrationReport summarizes a run of Migrate.
+type MigrationReport struct {
+	// From is the schema version the stored documents had before the run
+	From int
+	// To is the schema version the stored documents have after the run
+	To int
+	// DryRun is true if nothing was written
+	DryRun bool
+	// Results lists the pending migrations in the order they ran
+	Results []MigrationResult
+}
+
+// String renders the report as one line per migration, e.g.
+// "migration 2 (Store ECTS as a decimal number): 14 document(s) changed".
+func (r MigrationReport) String() string {
+	if len(r.Results) == 0 {
+		return fmt.Sprintf("schema is up to date (version %d)", r.From)
+	}
+
+	verb := "changed"
+	if r.DryRun {
+		verb = "would change"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "schema version %d -> %d", r.From, r.To)
+	if r.DryRun {
+		b.WriteString(" (dry run, nothing written)")
+	}
+	for _, res := range r.Results {
+		fmt.Fprintf(&b, "\n  migration %d (%s): %d document(s) %s", res.Migration.Version, res.Migration.Description, res.Changed, verb)
+	}
+	return b.String()
+}
+
+// Migrate brings the stored course documents up to the current schema version by
+// running every pending migration in order. All pending migrations are applied to each
+// document in one pass, so a dry run reports exactly what a real run would change.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the migration
+//	store: The store whose documents are migrated
+//	dryRun: If true, nothing is written and the report tells what would change
+//
+// Returns:
+//
+//	A report of the pending migrations, or an error if the stored schema is newer than
+//	this build supports (wrapping ErrValidation) or the store fails.
+func Migrate(ctx context.Context, store Migratable, dryRun bool) (MigrationReport, error) {
+	from, err := store.StoredSchemaVersion(ctx)
+	if err != nil {
+		return MigrationReport{}, err
+	}
+	report := MigrationReport{From: from, To: from, DryRun: dryRun}
+	if from > SchemaVersion() {
+		return report, fmt.Errorf("%w: stored data has schema version %d, but this build only supports up to %d", ErrValidation, from, SchemaVersion())
+	}
+
+	// Collect the migrations the stored documents haven't seen yet
+	for _, m := range migrations {
+		if m.Version > from {
+			report.Results = append(report.Results, MigrationResult{Migration: m})
+		}
+	}
+	if len(report.Results) == 0 {
+		return report, nil
+	}
+
+	// Run every pending migration on each document, counting changes per migration
+	_, err = store.RewriteDocuments(ctx, func(uni string, doc Document) (bool, error) {
+		changed := false
+		for i := range report.Results {
+			res := &report.Results[i]
+			ok, err := res.Migration.Up(uni, doc)
+			if err != nil {
+				return false, fmt.Errorf("migration %d: %w", res.Migration.Version, err)
+			}
+			if ok {
+				res.Changed++
+				changed = true
+			}
+		}
+		return changed, nil
+	}, dryRun)
+	if err != nil {
+		return report, err
+	}
+
+	if dryRun {
+		report.To = SchemaVersion()
+		return report, nil
+	}
+	if err := store.SetStoredSchemaVersion(ctx, SchemaVersion()); err != nil {
+		return report, err
+	}
+	report.To = SchemaVersion()
+	return report, nil
+}
+
+// backfillUniversity stores the university a course belongs to inside the document,
+// so exported documents stay self-describing outside their collection.
+func backfillUniversity(uni string, doc Document) (bool, error) {
+	if existing, ok := doc["University"].(string); ok && existing != "" {
+		return false, nil
+	}
+	doc["University"] = uni
+	return true, nil
+}
+
+// ectsToDecimal converts integer ECTS values to decimal numbers, so half credits can be stored.
+func ectsToDecimal(uni string, doc Document) (bool, error) {
+	switch v := doc["ECTS"].(type) {
+	case int32:
+		doc["ECTS"] = float64(v)
+	case int64:
+		doc["ECTS"] = float64(v)
+	case int:
+		doc["ECTS"] = float64(v)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// backfillRevision gives courses stored before revisions existed revision 1,
+// so updates that check the revision can match them.
+func backfillRevision(uni string, doc Document) (bool, error) {
+	switch v := doc["Revision"].(type) {
+	case int32:
+		if v > 0 {
+			return false, nil
+		}
+	case int64:
+		if v > 0 {
+			return false, nil
+		}
+	case float64:
+		// Revisions read from the JSON data file
+		if v > 0 {
+			return false, nil
+		}
+	}
+	doc["Revision"] = int64(1)
+	return true, nil
+}
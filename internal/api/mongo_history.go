@@ -0,0 +1,120 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context" // Used for context management in MongoDB operations
+	"fmt"     // Formatted I/O package
+
+	// Third-party packages
+	"go.mongodb.org/mongo-driver/v2/bson"          // BSON encoding/decoding for MongoDB
+	"go.mongodb.org/mongo-driver/v2/mongo"         // MongoDB driver
+	"go.mongodb.org/mongo-driver/v2/mongo/options" // MongoDB query options
+)
+
+// historyCollection is the collection in the "CourseInfo" database holding the history
+// of every university. Course collections are named after universities, so this name
+// must not match any university collection.
+const historyCollection = "History"
+
+// MongoHistory is a HistoryStore backed by the "History" collection of the MongoDB database.
+type MongoHistory struct {
+	// client is the MongoDB client connection
+	client *mongo.Client
+}
+
+// NewMongoHistory creates a HistoryStore that reads and writes entries through the given client.
+func NewMongoHistory(client *mongo.Client) *MongoHistory {
+	return &MongoHistory{client: client}
+}
+
+// collection returns the MongoDB collection holding the history entries.
+func (h *MongoHistory) collection() *mongo.Collection {
+	return h.client.Database("CourseInfo").Collection(historyCollection)
+}
+
+// RecordHistory inserts a history entry document.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the insert
+//	entry: The entry to record; an ID is generated if it has none
+//
+// Returns:
+//
+//	An error wrapping ErrUnavailable if the insert fails.
+func (h *MongoHistory) RecordHistory(ctx context.Context, entry HistoryEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = bson.NewObjectID()
+	}
+	if _, err := h.collection().InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record history: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// ImportHistory inserts the entries of a university in the given order, deleting the
+// university's history first if replace is set. Otherwise entries whose ID is already
+// stored are skipped.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the writes
+//	uni: The university the entries belong to
+//	entries: The entries to insert, oldest first
+//	replace: Whether to delete the stored history of the university first
+//
+// Returns:
+//
+//	The number of inserted entries, or an error wrapping ErrUnavailable if a write fails.
+func (h *MongoHistory) ImportHistory(ctx context.Context, uni string, entries []HistoryEntry, replace bool) (int, error) {
+	coll := h.collection()
+	if replace {
+		if _, err := coll.DeleteMany(ctx, bson.D{{Key: "University", Value: uni}}); err != nil {
+			return 0, fmt.Errorf("failed to clear history: %w: %w", ErrUnavailable, err)
+		}
+	}
+
+	imported := 0
+	for _, e := range entries {
+		if e.ID.IsZero() {
+			e.ID = bson.NewObjectID()
+		}
+		e.University = uni
+		_, err := coll.InsertOne(ctx, e)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return imported, fmt.Errorf("failed to restore history: %w: %w", ErrUnavailable, err)
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// GetHistory retrieves the history entries of a university, newest first.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the query
+//	uni: The university whose history is retrieved
+//
+// Returns:
+//
+//	The entries of the university, or an error wrapping ErrUnavailable if the query fails.
+func (h *MongoHistory) GetHistory(ctx context.Context, uni string) ([]HistoryEntry, error) {
+	// Sort by time, then by ID so entries recorded within the same millisecond keep their order
+	opts := options.Find().SetSort(bson.D{{Key: "Time", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := h.collection().Find(ctx, bson.D{{Key: "University", Value: uni}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w: %w", ErrUnavailable, err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []HistoryEntry
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w: %w", ErrUnavailable, err)
+	}
+	return results, nil
+}
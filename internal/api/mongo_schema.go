@@ -0,0 +1,320 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"bytes"   // Comparison of stored and expected validators
+	"context" // Used for context management in MongoDB operations
+	"fmt"     // Formatted I/O package
+	"strings" // String building for reports
+
+	// Third-party packages
+	"go.mongodb.org/mongo-driver/v2/bson"          // BSON encoding/decoding for MongoDB
+	"go.mongodb.org/mongo-driver/v2/mongo"         // MongoDB driver
+	"go.mongodb.org/mongo-driver/v2/mongo/options" // MongoDB collection and index options
+
+	// Internal packages
+	"UniGrades/internal/university" // Known universities
+)
+
+// nameIndex is the name of the unique index on course names and years, also used to resolve
+// names to courses. Its keys include DeletedAt, so two active courses can't share a name in
+// the same year, while a retake (the same name in another year) and trashed courses (each
+// with its own deletion time) never block adding a course.
+const nameIndex = "name_year_unique"
+
+// retiredIndex is an index earlier releases created that now gets in the way.
+type retiredIndex struct {
+	// name is the index name
+	name string
+	// reason tells why the index is dropped, shown in the drift report
+	reason string
+}
+
+// retiredIndexes lists the indexes dropped from every course collection. Each university
+// has its own collection, so the unique name index enforced name/university uniqueness;
+// it is dropped because a retaken course keeps its name. The plain name index that
+// replaced it is covered by the unique index on names and years.
+var retiredIndexes = []retiredIndex{
+	{name: "name_unique", reason: "rejected retakes, replaced by " + nameIndex},
+	{name: "name", reason: "replaced by " + nameIndex},
+}
+
+// yearIndex is the name of the index on course years, used when courses are listed per year.
+const yearIndex = "year"
+
+// courseIndex describes an index every course collection must have.
+type courseIndex struct {
+	// name is the index name
+	name string
+	// keys are the indexed fields with their directions
+	keys bson.D
+	// unique is true if no two documents may share the indexed values
+	unique bool
+}
+
+// courseIndexes lists the indexes every course collection must have.
+var courseIndexes = []courseIndex{
+	{name: nameIndex, keys: bson.D{{Key: "Name", Value: int32(1)}, {Key: "Year", Value: int32(1)}, {Key: "DeletedAt", Value: int32(1)}}, unique: true},
+	{name: yearIndex, keys: bson.D{{Key: "Year", Value: int32(1)}}},
+}
+
+// model returns the index model that creates the index.
+func (ix courseIndex) model() mongo.IndexModel {
+	opts := options.Index().SetName(ix.name)
+	if ix.unique {
+		opts.SetUnique(true)
+	}
+	return mongo.IndexModel{Keys: ix.keys, Options: opts}
+}
+
+// matches reports whether an existing index has the keys and uniqueness of this one.
+// Key directions are compared as numbers, since the server may store them with another type.
+func (ix courseIndex) matches(spec mongo.IndexSpecification) bool {
+	if (spec.Unique != nil && *spec.Unique) != ix.unique {
+		return false
+	}
+
+	have, err := spec.KeysDocument.Elements()
+	if err != nil || len(have) != len(ix.keys) {
+		return false
+	}
+	for i, e := range have {
+		direction, ok := e.Value().AsFloat64OK()
+		if !ok || e.Key() != ix.keys[i].Key || direction != float64(ix.keys[i].Value.(int32)) {
+			return false
+		}
+	}
+	return true
+}
+
+// courseValidator returns the $jsonSchema validator matching the Course type and the
+// rules of validateCourse, so other clients can't store documents the app would skip.
+func courseValidator() bson.D {
+	number := bson.A{"double", "int", "long", "decimal"}
+	return bson.D{{Key: "$jsonSchema", Value: bson.D{
+		{Key: "bsonType", Value: "object"},
+		{Key: "required", Value: bson.A{"Name", "Year", "Grade", "ECTS"}},
+		{Key: "properties", Value: bson.D{
+			{Key: "Name", Value: bson.D{{Key: "bsonType", Value: "string"}, {Key: "minLength", Value: int32(1)}}},
+			{Key: "Year", Value: bson.D{{Key: "bsonType", Value: bson.A{"int", "long"}}, {Key: "minimum", Value: int32(1)}}},
+			{Key: "Grade", Value: bson.D{{Key: "bsonType", Value: number}, {Key: "minimum", Value: int32(0)}, {Key: "maximum", Value: int32(10)}}},
+			{Key: "ECTS", Value: bson.D{{Key: "bsonType", Value: number}, {Key: "minimum", Value: int32(0)}}},
+			{Key: "University", Value: bson.D{{Key: "bsonType", Value: "string"}}},
+			{Key: "DeletedAt", Value: bson.D{{Key: "bsonType", Value: "date"}}},
+			{Key: "SealedGrade", Value: bson.D{{Key: "bsonType", Value: "string"}}},
+			{Key: "Revision", Value: bson.D{{Key: "bsonType", Value: bson.A{"int", "long"}}, {Key: "minimum", Value: int32(1)}}},
+		}},
+	}}}
+}
+
+// SchemaDrift is a difference between a course collection and the schema the app expects.
+type SchemaDrift struct {
+	// University is the university whose collection drifted
+	University string
+	// Problem describes the difference
+	Problem string
+	// Fixed is true if the difference was corrected
+	Fixed bool
+}
+
+// SchemaReport lists the drift found by EnsureSchema.
+type SchemaReport struct {
+	// Drift lists every difference found, in the order the collections were checked
+	Drift []SchemaDrift
+}
+
+// String renders the report as one line per difference, e.g.
+// "TU/e: validator missing (fixed)".
+func (r SchemaReport) String() string {
+	if len(r.Drift) == 0 {
+		return "course collections match the schema"
+	}
+
+	var b strings.Builder
+	b.WriteString("schema drift found:")
+	for _, d := range r.Drift {
+		fmt.Fprintf(&b, "\n  %s: %s", d.University, d.Problem)
+		if d.Fixed {
+			b.WriteString(" (fixed)")
+		}
+	}
+	return b.String()
+}
+
+// SchemaEnforcer is implemented by stores that can enforce the course schema
+// on the database itself, independently of the app.
+type SchemaEnforcer interface {
+	// EnsureSchema installs the course validator and indexes, reporting any drift it finds.
+	EnsureSchema(ctx context.Context) (SchemaReport, error)
+}
+
+// EnsureSchema makes sure the collection of every known university has the course
+// validator and the indexes the app relies on. Missing or outdated validators and
+// indexes are installed; documents that violate the validator and indexes that
+// can't be built (e.g. because of duplicate names) are reported but left alone.
+// Retired indexes are dropped.
+// The validator uses the "moderate" level, so invalid documents that already exist
+// can still be edited or deleted.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the commands
+//
+// Returns:
+//
+//	A report of the drift found, or an error wrapping ErrUnavailable if the database
+//	can't be inspected.
+func (s *MongoStore) EnsureSchema(ctx context.Context) (SchemaReport, error) {
+	var report SchemaReport
+	for _, uni := range university.Names() {
+		drift, err := s.ensureCollectionSchema(ctx, uni)
+		if err != nil {
+			return report, err
+		}
+		report.Drift = append(report.Drift, drift...)
+	}
+	return report, nil
+}
+
+// ensureCollectionSchema checks and repairs the validator and indexes of one university's collection.
+func (s *MongoStore) ensureCollectionSchema(ctx context.Context, uni string) ([]SchemaDrift, error) {
+	db := s.client.Database("CourseInfo")
+	name := collectionName(uni)
+	validator := courseValidator()
+
+	specs, err := db.ListCollectionSpecifications(ctx, bson.D{{Key: "name", Value: name}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect collection %s: %w: %w", name, ErrUnavailable, err)
+	}
+
+	// A collection that doesn't exist yet is simply created with the validator
+	var drift []SchemaDrift
+	if len(specs) == 0 {
+		opts := options.CreateCollection().
+			SetValidator(validator).
+			SetValidationLevel("moderate").
+			SetValidationAction("error")
+		if err := db.CreateCollection(ctx, name, opts); err != nil {
+			return nil, fmt.Errorf("failed to create collection %s: %w: %w", name, ErrUnavailable, err)
+		}
+	} else if problem := validatorDrift(specs[0].Options, validator); problem != "" {
+		cmd := bson.D{
+			{Key: "collMod", Value: name},
+			{Key: "validator", Value: validator},
+			{Key: "validationLevel", Value: "moderate"},
+			{Key: "validationAction", Value: "error"},
+		}
+		err := db.RunCommand(ctx, cmd).Err()
+		if err != nil {
+			problem += fmt.Sprintf(", failed to install it: %v", err)
+		}
+		drift = append(drift, SchemaDrift{University: uni, Problem: problem, Fixed: err == nil})
+	}
+
+	indexDrift, err := s.ensureIndexes(ctx, uni)
+	if err != nil {
+		return nil, err
+	}
+	drift = append(drift, indexDrift...)
+
+	// Report documents written before the validator was in place
+	coll := s.collection(uni)
+	invalid, err := coll.CountDocuments(ctx, bson.D{{Key: "$nor", Value: bson.A{validator}}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count invalid courses: %w: %w", ErrUnavailable, err)
+	}
+	if invalid > 0 {
+		drift = append(drift, SchemaDrift{University: uni, Problem: fmt.Sprintf("%d stored course(s) violate the validator", invalid)})
+	}
+	return drift, nil
+}
+
+// validatorDrift compares the collection options with the expected validator settings
+// and describes the difference, or returns an empty string if they match.
+func validatorDrift(collOptions bson.Raw, validator bson.D) string {
+	stored, ok := collOptions.Lookup("validator").DocumentOK()
+	if !ok {
+		return "validator missing"
+	}
+	expected, err := bson.Marshal(validator)
+	if err != nil || !bytes.Equal(stored, expected) {
+		return "validator differs from the course schema"
+	}
+	if level, _ := collOptions.Lookup("validationLevel").StringValueOK(); level != "" && level != "moderate" {
+		return fmt.Sprintf("validation level is %q instead of \"moderate\"", level)
+	}
+	if action, _ := collOptions.Lookup("validationAction").StringValueOK(); action != "" && action != "error" {
+		return fmt.Sprintf("validation action is %q instead of \"error\"", action)
+	}
+	return ""
+}
+
+// ensureIndexes creates the missing course indexes of a university's collection,
+// rebuilds those whose definition changed and drops retired ones. Unknown indexes
+// are reported but kept.
+func (s *MongoStore) ensureIndexes(ctx context.Context, uni string) ([]SchemaDrift, error) {
+	indexes := s.collection(uni).Indexes()
+	existing, err := indexes.ListSpecifications(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w: %w", ErrUnavailable, err)
+	}
+	byName := make(map[string]mongo.IndexSpecification, len(existing))
+	for _, spec := range existing {
+		byName[spec.Name] = spec
+	}
+
+	var drift []SchemaDrift
+	known := map[string]bool{"_id_": true}
+	for _, ix := range courseIndexes {
+		known[ix.name] = true
+
+		problem := ""
+		spec, ok := byName[ix.name]
+		switch {
+		case !ok:
+			problem = fmt.Sprintf("index %s missing", ix.name)
+		case !ix.matches(spec):
+			problem = fmt.Sprintf("index %s differs from the expected definition", ix.name)
+			if err := indexes.DropOne(ctx, ix.name); err != nil {
+				drift = append(drift, SchemaDrift{University: uni, Problem: fmt.Sprintf("%s, failed to drop it: %v", problem, err)})
+				continue
+			}
+		default:
+			continue
+		}
+
+		// Building a unique index fails while duplicates exist; report it so they can be cleaned up
+		if _, err := indexes.CreateOne(ctx, ix.model()); err != nil {
+			drift = append(drift, SchemaDrift{University: uni, Problem: fmt.Sprintf("%s, failed to create it: %v", problem, err)})
+			continue
+		}
+		drift = append(drift, SchemaDrift{University: uni, Problem: problem, Fixed: true})
+	}
+
+	for _, retired := range retiredIndexes {
+		if _, ok := byName[retired.name]; !ok {
+			continue
+		}
+		known[retired.name] = true
+		problem := fmt.Sprintf("retired index %s present (%s)", retired.name, retired.reason)
+		if err := indexes.DropOne(ctx, retired.name); err != nil {
+			drift = append(drift, SchemaDrift{University: uni, Problem: fmt.Sprintf("%s, failed to drop it: %v", problem, err)})
+			continue
+		}
+		drift = append(drift, SchemaDrift{University: uni, Problem: problem, Fixed: true})
+	}
+
+	for _, spec := range existing {
+		if !known[spec.Name] {
+			drift = append(drift, SchemaDrift{University: uni, Problem: fmt.Sprintf("unexpected index %s", spec.Name)})
+		}
+	}
+	return drift, nil
+}
+
+// isDuplicateName reports whether a write failed because another active course
+// already has the same name in the same year.
+func isDuplicateName(err error) bool {
+	return mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), nameIndex)
+}
@@ -0,0 +1,693 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context" // Used for context management in MongoDB operations
+	"errors"  // Error inspection
+	"fmt"     // Formatted I/O package
+	"strings" // String manipulation
+	"time"    // Deletion timestamps
+	"unicode" // Character classification
+
+	// Third-party packages
+	"go.mongodb.org/mongo-driver/v2/bson"          // BSON encoding/decoding for MongoDB
+	"go.mongodb.org/mongo-driver/v2/mongo"         // MongoDB driver
+	"go.mongodb.org/mongo-driver/v2/mongo/options" // MongoDB query options
+
+	// Internal packages
+	"UniGrades/internal/university" // Known universities
+)
+
+// MongoStore is a CourseStore backed by a MongoDB database.
+// Each university has its own collection in the "CourseInfo" database, named after
+// the university with non-alphanumeric characters removed (e.g. "TU/e" -> "TUe").
+type MongoStore struct {
+	// client is the MongoDB client connection
+	client *mongo.Client
+}
+
+// NewMongoStore creates a CourseStore that reads and writes courses through the given client.
+func NewMongoStore(client *mongo.Client) *MongoStore {
+	return &MongoStore{client: client}
+}
+
+// notTrashed matches course documents that have not been moved to the trash.
+var notTrashed = bson.E{Key: "DeletedAt", Value: bson.D{{Key: "$exists", Value: false}}}
+
+// inTrash matches course documents that have been moved to the trash.
+var inTrash = bson.E{Key: "DeletedAt", Value: bson.D{{Key: "$exists", Value: true}}}
+
+// collection returns the MongoDB collection holding the course documents of a university.
+func (s *MongoStore) collection(uni string) *mongo.Collection {
+	return s.client.Database("CourseInfo").Collection(collectionName(uni))
+}
+
+// collectionName converts a university name into its collection name
+// by keeping only letters and digits (e.g. "TU/e" -> "TUe").
+func collectionName(uni string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, uni)
+}
+
+// GetAllCourses retrieves all active courses of a university from the MongoDB database.
+// It queries the university's collection in the "CourseInfo" database and decodes
+// each document into a Course. Documents with missing or mistyped fields are skipped
+// and reported through a *MalformedError.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the query
+//	uni: The university whose courses are retrieved
+//
+// Returns:
+//
+//	A slice containing every valid course.
+//	A *MalformedError if some documents were skipped, or an error wrapping
+//	ErrUnavailable if the database query fails.
+func (s *MongoStore) GetAllCourses(ctx context.Context, uni string) ([]Course, error) {
+	// Execute a query to find all documents that are not in the trash
+	return s.findCourses(ctx, uni, bson.D{notTrashed})
+}
+
+// GetTrashedCourses retrieves all trashed courses of a university from the MongoDB database.
+// Malformed documents are skipped and reported exactly like in GetAllCourses.
+func (s *MongoStore) GetTrashedCourses(ctx context.Context, uni string) ([]Course, error) {
+	return s.findCourses(ctx, uni, bson.D{inTrash})
+}
+
+// findCourses decodes every course document of a university that matches the filter.
+// Documents with missing or mistyped fields are skipped and reported through a *MalformedError.
+func (s *MongoStore) findCourses(ctx context.Context, uni string, filter bson.D) ([]Course, error) {
+	coll := s.collection(uni)
+
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query courses: %w: %w", ErrUnavailable, err)
+	}
+	defer cursor.Close(ctx)
+
+	// Decode documents one at a time so a single bad document doesn't fail the whole load
+	var results []Course
+	var malformed []MalformedDocument
+	for cursor.Next(ctx) {
+		var course Course
+		if err := cursor.Decode(&course); err != nil {
+			malformed = append(malformed, MalformedDocument{ID: rawDocumentID(cursor.Current), Reason: err.Error()})
+			continue
+		}
+		if err := validateCourse(course); err != nil {
+			malformed = append(malformed, MalformedDocument{ID: course.ID.Hex(), Reason: err.Error()})
+			continue
+		}
+		results = append(results, course)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read courses: %w: %w", ErrUnavailable, err)
+	}
+
+	if len(malformed) > 0 {
+		return results, &MalformedError{Documents: malformed}
+	}
+	return results, nil
+}
+
+// rawDocumentID returns the hex ObjectID of a raw document, or empty string if it has none.
+func rawDocumentID(doc bson.Raw) string {
+	id, ok := doc.Lookup("_id").ObjectIDOK()
+	if !ok {
+		return ""
+	}
+	return id.Hex()
+}
+
+// GetStats computes the statistics of the active courses in the database with a single
+// aggregation pipeline, so no course has to be transferred: courses are grouped by year
+// and summed there, and the overall figures are derived from the per-year sums.
+// Documents that don't satisfy the collection validator are left out, just like
+// GetAllCourses skips malformed documents.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the query
+//	uni: The university whose courses are summarized
+//
+// Returns:
+//
+//	The statistics, or an error wrapping ErrUnavailable if the aggregation fails.
+func (s *MongoStore) GetStats(ctx context.Context, uni string) (CourseStats, error) {
+	cursor, err := s.collection(uni).Aggregate(ctx, statsPipeline())
+	if err != nil {
+		return CourseStats{}, fmt.Errorf("failed to aggregate courses: %w: %w", ErrUnavailable, err)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []statsGroup
+	if err := cursor.All(ctx, &groups); err != nil {
+		return CourseStats{}, fmt.Errorf("failed to read course statistics: %w: %w", ErrUnavailable, err)
+	}
+	return statsFromGroups(uni, groups), nil
+}
+
+// statsPipeline returns the aggregation pipeline of GetStats: valid active courses,
+// grouped by year into one statsGroup document each.
+func statsPipeline() mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{notTrashed, courseValidator()[0]}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$Year"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "gradeSum", Value: bson.D{{Key: "$sum", Value: "$Grade"}}},
+			{Key: "weightedSum", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$multiply", Value: bson.A{"$Grade", "$ECTS"}}}}}},
+			{Key: "ects", Value: bson.D{{Key: "$sum", Value: "$ECTS"}}},
+		}}},
+	}
+}
+
+// statsGroup is a document produced by the $group stage of statsPipeline.
+type statsGroup struct {
+	Year        int     `bson:"_id"`
+	Count       int     `bson:"count"`
+	GradeSum    float64 `bson:"gradeSum"`
+	WeightedSum float64 `bson:"weightedSum"`
+	ECTS        float64 `bson:"ects"`
+}
+
+// statsFromGroups derives the statistics of a university from the per-year groups of statsPipeline.
+func statsFromGroups(uni string, groups []statsGroup) CourseStats {
+	perYear := make(map[int]statsTotals, len(groups))
+	for _, g := range groups {
+		perYear[g.Year] = statsTotals{Count: g.Count, GradeSum: g.GradeSum, WeightedSum: g.WeightedSum, ECTS: g.ECTS}
+	}
+	return buildStats(uni, perYear)
+}
+
+// AddCourse inserts a new course document into the MongoDB database.
+// It creates a new entry in the university's collection under the "CourseInfo" database.
+// If the course already has an ID, the document is inserted under that ID.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the query
+//	uni: The university the course belongs to
+//	course: A Course struct containing the course information to be added
+//
+// Returns:
+//
+//	A string containing the MongoDB ObjectID of the newly inserted document (in hex format).
+//	An error wrapping ErrValidation if the course is invalid, its ID is already in use or
+//	another active course has its name in the same year (once EnsureSchema installed the
+//	name index), or ErrUnavailable if insertion fails.
+func (s *MongoStore) AddCourse(ctx context.Context, uni string, course Course) (string, error) {
+	coll := s.collection(uni)
+
+	// Reject invalid courses before they reach the database
+	if err := validateCourse(course); err != nil {
+		return "", err
+	}
+
+	// Insert the course document into the collection, tagged with its university
+	course.University = uni
+	if course.Revision == 0 {
+		course.Revision = 1
+	}
+	result, err := coll.InsertOne(ctx, course)
+	if isDuplicateName(err) {
+		return "", fmt.Errorf("%w: a course named '%s' already exists in year %d", ErrValidation, course.Name, course.Year)
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return "", fmt.Errorf("%w: course ID %s is already in use", ErrValidation, course.ID.Hex())
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to insert course: %w: %w", ErrUnavailable, err)
+	}
+
+	// Return the automatically generated MongoDB ObjectID as a hexadecimal string
+	return result.InsertedID.(bson.ObjectID).Hex(), nil
+}
+
+// ImportCourses inserts the courses exactly as given, including their IDs, revisions and
+// deletion times. With replace, every document of the university's collection is deleted
+// first; otherwise courses whose ID is in use, or whose name is taken by an active course
+// of the same year (once EnsureSchema installed the name index), are skipped. The replacement is not atomic:
+// if an insert fails, the courses inserted until then stay.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the writes
+//	uni: The university the courses belong to
+//	courses: The courses to insert, active and trashed
+//	replace: Whether to delete the stored courses of the university first
+//
+// Returns:
+//
+//	The number of inserted courses, or an error wrapping ErrUnavailable if a write fails.
+func (s *MongoStore) ImportCourses(ctx context.Context, uni string, courses []Course, replace bool) (int, error) {
+	coll := s.collection(uni)
+	if replace {
+		if _, err := coll.DeleteMany(ctx, bson.D{}); err != nil {
+			return 0, fmt.Errorf("failed to clear courses: %w: %w", ErrUnavailable, err)
+		}
+	}
+
+	imported := 0
+	for _, c := range courses {
+		c.University = uni
+		if c.ID.IsZero() {
+			c.ID = bson.NewObjectID()
+		}
+		if c.Revision == 0 {
+			c.Revision = 1
+		}
+		_, err := coll.InsertOne(ctx, c)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return imported, fmt.Errorf("failed to restore course: %w: %w", ErrUnavailable, err)
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// GetCourse retrieves a single active course from the MongoDB database by its ID.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the query
+//	uni: The university the course belongs to
+//	id: The course ID in hex format
+//
+// Returns:
+//
+//	The course, or an error wrapping ErrValidation if the ID is invalid or the stored
+//	document is malformed, ErrNotFound if no course has the ID, or ErrUnavailable
+//	if the query fails.
+func (s *MongoStore) GetCourse(ctx context.Context, uni, id string) (Course, error) {
+	coll := s.collection(uni)
+
+	oid, err := ParseID(id)
+	if err != nil {
+		return Course{}, err
+	}
+
+	var course Course
+	err = coll.FindOne(ctx, bson.D{{Key: "_id", Value: oid}, notTrashed}).Decode(&course)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Course{}, fmt.Errorf("course '%s' %w", id, ErrNotFound)
+	}
+	var decodeErr *bson.DecodeError
+	if errors.As(err, &decodeErr) {
+		return Course{}, &MalformedError{Documents: []MalformedDocument{{ID: id, Reason: err.Error()}}}
+	}
+	if err != nil {
+		return Course{}, fmt.Errorf("failed to query course: %w: %w", ErrUnavailable, err)
+	}
+	if err := validateCourse(course); err != nil {
+		return Course{}, &MalformedError{Documents: []MalformedDocument{{ID: id, Reason: err.Error()}}}
+	}
+	return course, nil
+}
+
+// DeleteCourse moves a course to the trash by stamping its document with the deletion time.
+// The document stays in the collection until it is restored or purged.
+// If no active course with the specified ID and revision exists, an error is returned.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the query
+//	uni: The university the course belongs to
+//	id: The ID of the course to delete, in hex format
+//	revision: The revision the caller last saw, or AnyRevision
+//
+// Returns:
+//
+//	An error wrapping ErrValidation if the ID is invalid, ErrUnavailable if the update
+//	fails, ErrNotFound if the course is not found, or a *ConflictError if the course
+//	has another revision. Returns nil on success.
+func (s *MongoStore) DeleteCourse(ctx context.Context, uni, id string, revision int64) error {
+	coll := s.collection(uni)
+
+	oid, err := ParseID(id)
+	if err != nil {
+		return err
+	}
+
+	// Mark the active course with the matching ID and revision as trashed
+	result, err := coll.UpdateOne(
+		ctx,
+		revisionFilter(oid, revision),
+		bson.D{
+			{Key: "$set", Value: bson.D{{Key: "DeletedAt", Value: time.Now().UTC()}}},
+			{Key: "$inc", Value: bson.D{{Key: "Revision", Value: 1}}},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete course: %w: %w", ErrUnavailable, err)
+	}
+
+	// Check if the course was actually found and trashed
+	if result.MatchedCount == 0 {
+		return s.unmatched(ctx, uni, id, revision)
+	}
+
+	return nil
+}
+
+// RestoreCourse moves a trashed course back to the active courses by removing its deletion time.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the query
+//	uni: The university the course belongs to
+//	id: The ID of the trashed course, in hex format
+//
+// Returns:
+//
+//	An error wrapping ErrValidation if the ID is invalid or an active course has the same name
+//	and year, ErrUnavailable if the update fails, or ErrNotFound if no trashed course has the ID.
+//	Returns nil on success.
+func (s *MongoStore) RestoreCourse(ctx context.Context, uni, id string) error {
+	coll := s.collection(uni)
+
+	oid, err := ParseID(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := coll.UpdateOne(
+		ctx,
+		bson.D{{Key: "_id", Value: oid}, inTrash},
+		bson.D{
+			{Key: "$unset", Value: bson.D{{Key: "DeletedAt", Value: ""}}},
+			{Key: "$inc", Value: bson.D{{Key: "Revision", Value: 1}}},
+		},
+	)
+	if isDuplicateName(err) {
+		return fmt.Errorf("%w: another active course has the name and year of course '%s'", ErrValidation, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore course: %w: %w", ErrUnavailable, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("course '%s' %w in the trash", id, ErrNotFound)
+	}
+	return nil
+}
+
+// PurgeTrash permanently deletes the course documents trashed before the given time.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the query
+//	uni: The university whose trash is purged
+//	before: Courses trashed before this time are removed
+//
+// Returns:
+//
+//	The number of removed courses, or an error wrapping ErrUnavailable if the deletion fails.
+func (s *MongoStore) PurgeTrash(ctx context.Context, uni string, before time.Time) (int, error) {
+	coll := s.collection(uni)
+
+	result, err := coll.DeleteMany(ctx, bson.D{{Key: "DeletedAt", Value: bson.D{{Key: "$lt", Value: before}}}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w: %w", ErrUnavailable, err)
+	}
+	return int(result.DeletedCount), nil
+}
+
+// UpdateCourse modifies one or more fields of a course document in the MongoDB database,
+// matching the active document by its ID and revision. All fields are written with a
+// single $set that also increments the revision.
+// It performs type validation and conversion of every value before anything is written.
+// Supported fields are: Name (string), Grade (float), Year (int), and ECTS (float).
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the query
+//	uni: The university the course belongs to
+//	id: The ID of the course to update, in hex format
+//	revision: The revision the caller last saw, or AnyRevision
+//	updates: The field names (Name, Grade, Year, or ECTS) mapped to their new values as strings
+//
+// Returns:
+//
+//	An error wrapping ErrValidation if the ID or any field is invalid, a value cannot be converted
+//	or the new name and year are taken by another active course, ErrNotFound if the course is not found,
+//	a *ConflictError if the course has another revision, or ErrUnavailable if the update fails.
+//	Returns nil on success.
+func (s *MongoStore) UpdateCourse(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	// Convert and validate every value before anything is written
+	set, err := parseUpdates(updates)
+	if err != nil {
+		return err
+	}
+	return s.update(ctx, uni, id, revision, set)
+}
+
+// updateStored is UpdateCourse for WithEncryption, accepting the sealed grade too.
+func (s *MongoStore) updateStored(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	set, err := parseStoredUpdates(updates)
+	if err != nil {
+		return err
+	}
+	return s.update(ctx, uni, id, revision, set)
+}
+
+// update writes converted values to the course document with the given ID and revision.
+func (s *MongoStore) update(ctx context.Context, uni, id string, revision int64, set bson.D) error {
+	coll := s.collection(uni)
+
+	oid, err := ParseID(id)
+	if err != nil {
+		return err
+	}
+
+	// Execute a single update on the document matching the course ID and revision,
+	// so all fields change atomically and concurrent changes are detected
+	result, err := coll.UpdateOne(
+		ctx,
+		revisionFilter(oid, revision), // Filter: match by course ID and revision
+		bson.D{
+			{Key: "$set", Value: set}, // Update: set all fields to their new values
+			{Key: "$inc", Value: bson.D{{Key: "Revision", Value: 1}}},
+		},
+	)
+	if isDuplicateName(err) {
+		return fmt.Errorf("%w: another active course has the same name and year as course '%s'", ErrValidation, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update course: %w: %w", ErrUnavailable, err)
+	}
+
+	// Check if the course was actually found (MatchedCount > 0 means a document was matched)
+	if result.MatchedCount == 0 {
+		return s.unmatched(ctx, uni, id, revision)
+	}
+
+	return nil
+}
+
+// revisionFilter matches the active course document with the given ID and, unless
+// revision is AnyRevision, the given revision.
+func revisionFilter(oid bson.ObjectID, revision int64) bson.D {
+	filter := bson.D{{Key: "_id", Value: oid}, notTrashed}
+	if revision != AnyRevision {
+		filter = append(filter, bson.E{Key: "Revision", Value: revision})
+	}
+	return filter
+}
+
+// unmatched explains why a write filtered by revisionFilter matched no document:
+// either the course doesn't exist (ErrNotFound) or it was changed in the meantime
+// (*ConflictError with the current course).
+func (s *MongoStore) unmatched(ctx context.Context, uni, id string, revision int64) error {
+	current, err := s.GetCourse(ctx, uni, id)
+	if err != nil {
+		return err
+	}
+	return &ConflictError{Current: current, Expected: revision}
+}
+
+// WatchChanges opens a change stream on the university's collection and calls notify
+// for every insert, update, replace or delete, until ctx is done.
+// Change streams need a replica set or sharded cluster; on a standalone server,
+// opening the stream fails and the error is returned so the caller can poll instead.
+//
+// Parameters:
+//
+//	ctx: Context that closes the change stream when canceled
+//	uni: The university whose collection is watched
+//	notify: Called after each change; must not block
+//
+// Returns:
+//
+//	An error wrapping ErrUnavailable if the stream can't be opened or breaks,
+//	or nil once ctx is done.
+func (s *MongoStore) WatchChanges(ctx context.Context, uni string, notify func()) error {
+	stream, err := s.collection(uni).Watch(ctx, mongo.Pipeline{})
+	if err != nil {
+		return fmt.Errorf("failed to watch courses: %w: %w", ErrUnavailable, err)
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		notify()
+	}
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("course change stream failed: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// metaCollection is the collection in the "CourseInfo" database holding bookkeeping
+// documents such as the schema version. Like historyCollection, it must not match
+// any university collection.
+const metaCollection = "Meta"
+
+// schemaDocumentID is the _id of the document in metaCollection recording the schema version.
+const schemaDocumentID = "schema"
+
+// StoredSchemaVersion returns the schema version recorded in the "Meta" collection,
+// or 0 if none was recorded yet.
+func (s *MongoStore) StoredSchemaVersion(ctx context.Context) (int, error) {
+	var meta struct {
+		Version int `bson:"Version"`
+	}
+	err := s.client.Database("CourseInfo").Collection(metaCollection).
+		FindOne(ctx, bson.D{{Key: "_id", Value: schemaDocumentID}}).Decode(&meta)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w: %w", ErrUnavailable, err)
+	}
+	return meta.Version, nil
+}
+
+// SetStoredSchemaVersion records the schema version in the "Meta" collection.
+func (s *MongoStore) SetStoredSchemaVersion(ctx context.Context, version int) error {
+	_, err := s.client.Database("CourseInfo").Collection(metaCollection).UpdateOne(
+		ctx,
+		bson.D{{Key: "_id", Value: schemaDocumentID}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "Version", Value: version}}}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record schema version: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// encryptionDocumentID is the _id of the document in metaCollection holding the salt of
+// passphrase-derived keys.
+const encryptionDocumentID = "encryption"
+
+// EncryptionSalt returns the salt of passphrase-derived keys recorded in the "Meta"
+// collection, or nil if none was recorded yet.
+func (s *MongoStore) EncryptionSalt(ctx context.Context) ([]byte, error) {
+	var meta struct {
+		Salt []byte `bson:"Salt"`
+	}
+	err := s.client.Database("CourseInfo").Collection(metaCollection).
+		FindOne(ctx, bson.D{{Key: "_id", Value: encryptionDocumentID}}).Decode(&meta)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption salt: %w: %w", ErrUnavailable, err)
+	}
+	return meta.Salt, nil
+}
+
+// SetEncryptionSalt records the salt of passphrase-derived keys in the "Meta" collection.
+// Unless replace is set, a recorded salt is kept; if two clients record one at the same
+// time, both end up with the one that was stored first. Returns the recorded salt.
+func (s *MongoStore) SetEncryptionSalt(ctx context.Context, salt []byte, replace bool) ([]byte, error) {
+	operator := "$setOnInsert"
+	if replace {
+		operator = "$set"
+	}
+	var meta struct {
+		Salt []byte `bson:"Salt"`
+	}
+	record := func(upsert bool) error {
+		return s.client.Database("CourseInfo").Collection(metaCollection).FindOneAndUpdate(
+			ctx,
+			bson.D{{Key: "_id", Value: encryptionDocumentID}},
+			bson.D{{Key: operator, Value: bson.D{{Key: "Salt", Value: salt}}}},
+			options.FindOneAndUpdate().SetUpsert(upsert).SetReturnDocument(options.After),
+		).Decode(&meta)
+	}
+	err := record(true)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent upsert inserted the document first, so it is there now: keep the
+		// salt it recorded, or overwrite it once more without inserting
+		if !replace {
+			return s.EncryptionSalt(ctx)
+		}
+		err = record(false)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record encryption salt: %w: %w", ErrUnavailable, err)
+	}
+	return meta.Salt, nil
+}
+
+// RewriteDocuments passes every course document of every known university, including
+// trashed ones, to fn and replaces the documents it changed (unless dryRun is set).
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the queries
+//	fn: Transforms a document in place and reports whether it changed
+//	dryRun: If true, changed documents are counted but not written
+//
+// Returns:
+//
+//	The number of changed documents, or an error wrapping ErrUnavailable if a query fails.
+func (s *MongoStore) RewriteDocuments(ctx context.Context, fn func(uni string, doc Document) (bool, error), dryRun bool) (int, error) {
+	changed := 0
+	for _, uni := range university.Names() {
+		coll := s.collection(uni)
+
+		cursor, err := coll.Find(ctx, bson.D{})
+		if err != nil {
+			return changed, fmt.Errorf("failed to query courses: %w: %w", ErrUnavailable, err)
+		}
+		for cursor.Next(ctx) {
+			var doc bson.M
+			if err := cursor.Decode(&doc); err != nil {
+				cursor.Close(ctx)
+				return changed, fmt.Errorf("failed to read course: %w: %w", ErrUnavailable, err)
+			}
+			ok, err := fn(uni, Document(doc))
+			if err != nil {
+				cursor.Close(ctx)
+				return changed, fmt.Errorf("document %v: %w", doc["_id"], err)
+			}
+			if !ok {
+				continue
+			}
+			changed++
+			if dryRun {
+				continue
+			}
+			if _, err := coll.ReplaceOne(ctx, bson.D{{Key: "_id", Value: doc["_id"]}}, doc); err != nil {
+				cursor.Close(ctx)
+				return changed, fmt.Errorf("failed to rewrite course: %w: %w", ErrUnavailable, err)
+			}
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+		if err != nil {
+			return changed, fmt.Errorf("failed to read courses: %w: %w", ErrUnavailable, err)
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,90 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context" // Used for context management in MongoDB operations
+	"errors"  // Error inspection
+	"fmt"     // Formatted I/O package
+
+	// Third-party packages
+	"go.mongodb.org/mongo-driver/v2/bson"          // BSON encoding/decoding for MongoDB
+	"go.mongodb.org/mongo-driver/v2/mongo"         // MongoDB driver
+	"go.mongodb.org/mongo-driver/v2/mongo/options" // MongoDB query options
+)
+
+// tokensCollection is the collection in the "CourseInfo" database holding the API tokens.
+// Like historyCollection, it must not match any university collection.
+const tokensCollection = "Tokens"
+
+// MongoTokens is a TokenStore backed by the "Tokens" collection of the MongoDB database,
+// so every server sharing the database accepts the same tokens.
+type MongoTokens struct {
+	// client is the MongoDB client connection
+	client *mongo.Client
+}
+
+// NewMongoTokens creates a TokenStore that reads and writes tokens through the given client.
+func NewMongoTokens(client *mongo.Client) *MongoTokens {
+	return &MongoTokens{client: client}
+}
+
+// collection returns the MongoDB collection holding the tokens.
+func (t *MongoTokens) collection() *mongo.Collection {
+	return t.client.Database("CourseInfo").Collection(tokensCollection)
+}
+
+// SaveToken inserts a token document.
+// Returns an error wrapping ErrValidation if its ID is taken, or ErrUnavailable if the insert fails.
+func (t *MongoTokens) SaveToken(ctx context.Context, token APIToken) error {
+	_, err := t.collection().InsertOne(ctx, token)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: token ID %s already in use", ErrValidation, token.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save token: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// ListTokens returns every token, oldest first.
+// Returns an error wrapping ErrUnavailable if the query fails.
+func (t *MongoTokens) ListTokens(ctx context.Context) ([]APIToken, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "CreatedAt", Value: 1}})
+	cursor, err := t.collection().Find(ctx, bson.D{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w: %w", ErrUnavailable, err)
+	}
+	tokens := []APIToken{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w: %w", ErrUnavailable, err)
+	}
+	return tokens, nil
+}
+
+// FindToken returns the token whose secret has the given hash.
+// Returns an error wrapping ErrNotFound if there is none, or ErrUnavailable if the query fails.
+func (t *MongoTokens) FindToken(ctx context.Context, hash string) (APIToken, error) {
+	var token APIToken
+	err := t.collection().FindOne(ctx, bson.D{{Key: "Hash", Value: hash}}).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return APIToken{}, fmt.Errorf("token %w", ErrNotFound)
+	}
+	if err != nil {
+		return APIToken{}, fmt.Errorf("failed to check token: %w: %w", ErrUnavailable, err)
+	}
+	return token, nil
+}
+
+// RevokeToken deletes the token document with the given ID.
+// Returns an error wrapping ErrNotFound if there is none, or ErrUnavailable if the delete fails.
+func (t *MongoTokens) RevokeToken(ctx context.Context, id string) error {
+	result, err := t.collection().DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w: %w", ErrUnavailable, err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("token '%s' %w", id, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,669 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context"       // Cancellation of operations
+	"encoding/json" // JSON encoding of the outbox file
+	"errors"        // Error inspection
+	"fmt"           // Formatted I/O package
+	"io/fs"         // File system error values
+	"os"            // File operations
+	"path/filepath" // Path manipulation
+	"strings"       // String building for warnings
+	"sync"          // Mutex serializing writes and syncs
+	"time"          // Retry interval and outbox timestamps
+
+	// Third-party packages
+	"go.mongodb.org/mongo-driver/v2/bson" // ObjectID generation for courses added offline
+)
+
+// offlineRetryInterval is how long an OfflineStore serves the cache after the remote
+// store failed, before it tries the remote store again.
+const offlineRetryInterval = 30 * time.Second
+
+// OutboxEntry is a change made while the remote store was unreachable, waiting to be replayed.
+type OutboxEntry struct {
+	// ID identifies the entry
+	ID bson.ObjectID `json:"id"`
+	// University is the university the change applies to
+	University string `json:"university"`
+	// Operation is the kind of change (add, update, delete or restore)
+	Operation HistoryOperation `json:"operation"`
+	// Time is when the change was made
+	Time time.Time `json:"time"`
+	// Course is the added course, or the course as it was when the change was made;
+	// replaying compares it with the server to detect conflicting changes
+	Course Course `json:"course"`
+	// Updates are the new field values of an update
+	Updates map[string]string `json:"updates,omitempty"`
+}
+
+// Description names the change, e.g. "edit of 'Applied_Math'".
+func (e OutboxEntry) Description() string {
+	kind := map[HistoryOperation]string{OpAdd: "add", OpUpdate: "edit", OpDelete: "delete", OpRestore: "restore"}[e.Operation]
+	return fmt.Sprintf("%s of '%s'", kind, e.Course.Name)
+}
+
+// SyncConflict is a queued change that was not replayed because the course
+// changed on the server in the meantime.
+type SyncConflict struct {
+	// Entry is the change that was not applied
+	Entry OutboxEntry `json:"entry"`
+	// Reason says what changed on the server
+	Reason string `json:"reason"`
+	// Time is when the conflict was detected
+	Time time.Time `json:"time"`
+}
+
+// String describes the conflict, e.g. "edit of 'Applied_Math' not applied: Grade is 9 on the server".
+func (c SyncConflict) String() string {
+	return fmt.Sprintf("%s not applied: %s", c.Entry.Description(), c.Reason)
+}
+
+// conflictError reports that a queued change conflicts with the state of the server.
+type conflictError struct {
+	reason string
+}
+
+// Error returns the reason of the conflict.
+func (e *conflictError) Error() string {
+	return e.reason
+}
+
+// SyncWarning is returned together with the courses by OfflineStore.GetAllCourses when
+// they may not match the server: while it is unreachable and the cached courses are
+// shown, while changes wait to be replayed, or while conflicts wait to be reviewed.
+// Like *MalformedError, it is a warning: the courses returned with it are valid.
+type SyncWarning struct {
+	// Offline is true if the courses come from the cache
+	Offline bool
+	// Cause is the error that made the remote store count as unreachable
+	Cause error
+	// Pending is the number of changes waiting to be replayed
+	Pending int
+	// Conflicts is the number of changes that were not replayed because of conflicts
+	Conflicts int
+}
+
+// Error summarizes the sync state, e.g. "offline, showing cached courses; 2 change(s) waiting to sync".
+func (e *SyncWarning) Error() string {
+	var parts []string
+	if e.Offline {
+		parts = append(parts, "offline, showing cached courses")
+	}
+	if e.Pending > 0 {
+		parts = append(parts, fmt.Sprintf("%d change(s) waiting to sync", e.Pending))
+	}
+	if e.Conflicts > 0 {
+		parts = append(parts, fmt.Sprintf("%d change(s) conflicted with the server, see /sync", e.Conflicts))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// IsWarning reports whether an error returned by GetAllCourses is only a warning
+// (a *MalformedError or *SyncWarning), so the courses returned with it can be used,
+// or whether an error returned by a mutation is a *HistoryWarning, so the change was applied.
+func IsWarning(err error) bool {
+	var malformed *MalformedError
+	var syncWarning *SyncWarning
+	var historyWarning *HistoryWarning
+	return errors.As(err, &malformed) || errors.As(err, &syncWarning) || errors.As(err, &historyWarning)
+}
+
+// SyncStatus describes the changes an OfflineStore hasn't replayed yet.
+type SyncStatus struct {
+	// Offline is true if the remote store is currently treated as unreachable
+	Offline bool
+	// Cause is the error that made the remote store count as unreachable
+	Cause error
+	// Pending lists the changes waiting to be replayed, oldest first
+	Pending []OutboxEntry
+	// Conflicts lists the changes that were not replayed, oldest first
+	Conflicts []SyncConflict
+}
+
+// outboxData is the layout of the outbox file.
+type outboxData struct {
+	// Pending lists the changes waiting to be replayed, oldest first
+	Pending []OutboxEntry `json:"pending"`
+	// Conflicts lists the changes that were not replayed because of conflicts
+	Conflicts []SyncConflict `json:"conflicts"`
+}
+
+// OfflineStore is a CourseStore that keeps working while its remote store (MongoDB)
+// is unreachable. Every successful read refreshes a local cache of the courses; when
+// the remote store fails with ErrUnavailable or a timeout, reads are served from the
+// cache, and adds, edits, deletes and restores are applied to the cache and queued in
+// a persistent outbox. Queued changes are replayed in order once the remote store
+// responds again; a change whose course was changed on the server in the meantime
+// is not replayed but kept as a conflict for the user to review.
+type OfflineStore struct {
+	// mu serializes writes and syncs, so queued changes are replayed in order
+	mu sync.Mutex
+	// remote is the store the changes belong in
+	remote CourseStore
+	// cache holds the last-known courses and the changes made offline
+	cache *FileStore
+	// outboxPath is the location of the outbox file
+	outboxPath string
+	// outbox holds the queued changes and conflicts
+	outbox outboxData
+	// retryAt is when the remote store is tried again after it failed; zero while online
+	retryAt time.Time
+	// cause is the error that made the remote store count as unreachable
+	cause error
+}
+
+// DefaultCachePath returns the default location of the offline cache file,
+// next to the default data file (e.g. ~/.config/UniGrades/offline-cache.json).
+func DefaultCachePath() (string, error) {
+	path, err := DefaultFilePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "offline-cache.json"), nil
+}
+
+// OutboxFilePath returns the location of the outbox file kept next to a cache file,
+// e.g. "offline-cache.json" -> "offline-cache.outbox.json".
+func OutboxFilePath(cachePath string) string {
+	return strings.TrimSuffix(cachePath, filepath.Ext(cachePath)) + ".outbox.json"
+}
+
+// NewOfflineStore wraps a remote store with an offline cache kept at cachePath and an
+// outbox kept next to it. Both files are created on the first change.
+//
+// Parameters:
+//
+//	remote: The store to cache, typically MongoDB
+//	cachePath: Location of the JSON cache file
+//
+// Returns:
+//
+//	The OfflineStore, or an error if the cache or outbox file exists but cannot be read or parsed.
+func NewOfflineStore(remote CourseStore, cachePath string) (*OfflineStore, error) {
+	cache, err := NewFileStore(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	s := &OfflineStore{remote: remote, cache: cache, outboxPath: OutboxFilePath(cachePath)}
+
+	data, err := os.ReadFile(s.outboxPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.outboxPath, err)
+	}
+	if err := json.Unmarshal(data, &s.outbox); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.outboxPath, err)
+	}
+	return s, nil
+}
+
+// Unwrap returns the remote store.
+func (s *OfflineStore) Unwrap() CourseStore {
+	return s.remote
+}
+
+// GetAllCourses returns the active courses from the remote store and caches them.
+// While the remote store is unreachable, the cached courses are returned instead.
+// Either way, a *SyncWarning is returned with the courses as long as they are cached,
+// changes wait to be replayed, or conflicts wait to be reviewed.
+func (s *OfflineStore) GetAllCourses(ctx context.Context, uni string) ([]Course, error) {
+	if s.online(ctx) {
+		courses, err := s.remote.GetAllCourses(ctx, uni)
+		if err == nil || IsWarning(err) {
+			s.cache.ReplaceCourses(uni, courses, false)
+			if err != nil {
+				return courses, err
+			}
+			return courses, s.warning()
+		}
+		if !s.failed(err) {
+			return nil, err
+		}
+	}
+
+	courses, err := s.cache.GetAllCourses(ctx, uni)
+	if err != nil {
+		return nil, err
+	}
+	return courses, s.warning()
+}
+
+// GetTrashedCourses returns the trashed courses from the remote store and caches them,
+// or the cached trashed courses while the remote store is unreachable.
+func (s *OfflineStore) GetTrashedCourses(ctx context.Context, uni string) ([]Course, error) {
+	if s.online(ctx) {
+		courses, err := s.remote.GetTrashedCourses(ctx, uni)
+		if err == nil || IsWarning(err) {
+			s.cache.ReplaceCourses(uni, courses, true)
+			return courses, err
+		}
+		if !s.failed(err) {
+			return nil, err
+		}
+	}
+	return s.cache.GetTrashedCourses(ctx, uni)
+}
+
+// GetCourse returns the active course from the remote store,
+// or from the cache while the remote store is unreachable.
+func (s *OfflineStore) GetCourse(ctx context.Context, uni, id string) (Course, error) {
+	if s.online(ctx) {
+		course, err := s.remote.GetCourse(ctx, uni, id)
+		if !s.failed(err) {
+			return course, err
+		}
+	}
+	return s.cache.GetCourse(ctx, uni, id)
+}
+
+// GetStats returns the statistics computed by the remote store. While it is unreachable,
+// they are computed from the cached courses and returned with a *SyncWarning.
+func (s *OfflineStore) GetStats(ctx context.Context, uni string) (CourseStats, error) {
+	if s.online(ctx) {
+		stats, err := s.remote.GetStats(ctx, uni)
+		if !s.failed(err) {
+			return stats, err
+		}
+	}
+	stats, err := s.cache.GetStats(ctx, uni)
+	if err != nil {
+		return CourseStats{}, err
+	}
+	return stats, s.warning()
+}
+
+// AddCourse adds the course to the remote store, or, while it is unreachable,
+// to the cache with a newly assigned ID and queues it.
+func (s *OfflineStore) AddCourse(ctx context.Context, uni string, course Course) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.onlineLocked(ctx) {
+		id, err := s.remote.AddCourse(ctx, uni, course)
+		if err == nil || IsWarning(err) {
+			course.ID, _ = ParseID(id)
+			s.cache.AddCourse(ctx, uni, course)
+			return id, err
+		}
+		if !s.wentOffline(err) {
+			return "", err
+		}
+	}
+
+	// The ID is assigned here, so replaying the add creates the course under the same ID
+	if course.ID.IsZero() {
+		course.ID = bson.NewObjectID()
+	}
+	id, err := s.cache.AddCourse(ctx, uni, course)
+	if err != nil {
+		return "", err
+	}
+	course.University = uni
+	return id, s.enqueue(OutboxEntry{University: uni, Operation: OpAdd, Course: course})
+}
+
+// UpdateCourse updates the course in the remote store, or, while it is unreachable,
+// in the cache and queues the update together with the course as it was before.
+// While offline, the revision is checked against the cached course.
+func (s *OfflineStore) UpdateCourse(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	return s.update(ctx, uni, id, revision, updates, false)
+}
+
+// updateStored is UpdateCourse for WithEncryption, accepting the sealed grade too.
+// A queued update with a sealed grade is replayed through updateStored as well.
+func (s *OfflineStore) updateStored(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	return s.update(ctx, uni, id, revision, updates, true)
+}
+
+// update writes the updates through UpdateCourse, or through updateStored if stored is set.
+func (s *OfflineStore) update(ctx context.Context, uni, id string, revision int64, updates map[string]string, stored bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.onlineLocked(ctx) {
+		err := writeUpdates(ctx, s.remote, uni, id, revision, updates, stored)
+		if err == nil || IsWarning(err) {
+			writeUpdates(ctx, s.cache, uni, id, AnyRevision, updates, stored)
+			return err
+		}
+		if !s.wentOffline(err) {
+			return err
+		}
+	}
+
+	before, err := s.cache.GetCourse(ctx, uni, id)
+	if err != nil {
+		return err
+	}
+	if err := writeUpdates(ctx, s.cache, uni, id, revision, updates, stored); err != nil {
+		return err
+	}
+	return s.enqueue(OutboxEntry{University: uni, Operation: OpUpdate, Course: before, Updates: updates})
+}
+
+// DeleteCourse moves the course to the trash of the remote store, or, while it is
+// unreachable, to the trash of the cache and queues the deletion.
+// While offline, the revision is checked against the cached course.
+func (s *OfflineStore) DeleteCourse(ctx context.Context, uni, id string, revision int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.onlineLocked(ctx) {
+		err := s.remote.DeleteCourse(ctx, uni, id, revision)
+		if err == nil || IsWarning(err) {
+			s.cache.DeleteCourse(ctx, uni, id, AnyRevision)
+			return err
+		}
+		if !s.wentOffline(err) {
+			return err
+		}
+	}
+
+	before, err := s.cache.GetCourse(ctx, uni, id)
+	if err != nil {
+		return err
+	}
+	if err := s.cache.DeleteCourse(ctx, uni, id, revision); err != nil {
+		return err
+	}
+	return s.enqueue(OutboxEntry{University: uni, Operation: OpDelete, Course: before})
+}
+
+// RestoreCourse restores the trashed course in the remote store, or, while it is
+// unreachable, in the cache and queues the restore.
+func (s *OfflineStore) RestoreCourse(ctx context.Context, uni, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.onlineLocked(ctx) {
+		err := s.remote.RestoreCourse(ctx, uni, id)
+		if err == nil || IsWarning(err) {
+			s.cache.RestoreCourse(ctx, uni, id)
+			return err
+		}
+		if !s.wentOffline(err) {
+			return err
+		}
+	}
+
+	if err := s.cache.RestoreCourse(ctx, uni, id); err != nil {
+		return err
+	}
+	course, err := s.cache.GetCourse(ctx, uni, id)
+	if err != nil {
+		return err
+	}
+	return s.enqueue(OutboxEntry{University: uni, Operation: OpRestore, Course: course})
+}
+
+// PurgeTrash purges the trash of the remote store. Purges are not queued: while the
+// remote store is unreachable, the error is returned and the purge simply runs next time.
+func (s *OfflineStore) PurgeTrash(ctx context.Context, uni string, before time.Time) (int, error) {
+	purged, err := s.remote.PurgeTrash(ctx, uni, before)
+	if err == nil || IsWarning(err) {
+		s.cache.PurgeTrash(ctx, uni, before)
+	}
+	return purged, err
+}
+
+// Sync replays the queued changes to the remote store, oldest first. A change whose course
+// was changed on the server since is not replayed but recorded as a conflict. Syncing stops
+// at the first change that can't be replayed because the remote store is unreachable.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the replay
+//
+// Returns:
+//
+//	nil once every queued change was replayed or recorded as a conflict, or the error that
+//	stopped the replay (wrapping ErrUnavailable if the remote store is unreachable).
+func (s *OfflineStore) Sync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sync(ctx)
+}
+
+// Status returns the queued changes and conflicts.
+func (s *OfflineStore) Status() SyncStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return SyncStatus{
+		Offline:   !s.retryAt.IsZero(),
+		Cause:     s.cause,
+		Pending:   append([]OutboxEntry(nil), s.outbox.Pending...),
+		Conflicts: append([]SyncConflict(nil), s.outbox.Conflicts...),
+	}
+}
+
+// ClearConflicts forgets the recorded conflicts once the user has reviewed them.
+func (s *OfflineStore) ClearConflicts() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.outbox.Conflicts) == 0 {
+		return nil
+	}
+	s.outbox.Conflicts = nil
+	return s.saveOutbox()
+}
+
+// online reports whether the remote store should be tried, replaying queued changes first.
+func (s *OfflineStore) online(ctx context.Context) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.onlineLocked(ctx)
+}
+
+// onlineLocked is online for callers that hold s.mu. The remote store is skipped until
+// the retry time after a failure; after that, the queued changes are replayed before
+// anything else, so the remote store sees the changes in the order they were made.
+func (s *OfflineStore) onlineLocked(ctx context.Context) bool {
+	if !s.retryAt.IsZero() && time.Now().Before(s.retryAt) {
+		return false
+	}
+	if len(s.outbox.Pending) > 0 {
+		return s.sync(ctx) == nil
+	}
+	return true
+}
+
+// failed is wentOffline for callers that don't hold s.mu.
+func (s *OfflineStore) failed(err error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.wentOffline(err)
+}
+
+// wentOffline reports whether err means the remote store is unreachable,
+// and if so, serves the cache until the retry interval has passed. Callers hold s.mu.
+func (s *OfflineStore) wentOffline(err error) bool {
+	if !errors.Is(err, ErrUnavailable) && !errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	s.retryAt = time.Now().Add(offlineRetryInterval)
+	s.cause = err
+	return true
+}
+
+// warning returns the *SyncWarning describing the current sync state, or nil if
+// the remote store is reachable and nothing waits to be replayed or reviewed.
+func (s *OfflineStore) warning() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := &SyncWarning{
+		Offline:   !s.retryAt.IsZero(),
+		Cause:     s.cause,
+		Pending:   len(s.outbox.Pending),
+		Conflicts: len(s.outbox.Conflicts),
+	}
+	if !w.Offline && w.Pending == 0 && w.Conflicts == 0 {
+		return nil
+	}
+	return w
+}
+
+// enqueue appends a change to the outbox and saves it. Callers hold s.mu.
+func (s *OfflineStore) enqueue(entry OutboxEntry) error {
+	entry.ID = bson.NewObjectID()
+	entry.Time = time.Now().UTC()
+	s.outbox.Pending = append(s.outbox.Pending, entry)
+	return s.saveOutbox()
+}
+
+// sync replays the queued changes. Callers hold s.mu.
+func (s *OfflineStore) sync(ctx context.Context) error {
+	for len(s.outbox.Pending) > 0 {
+		entry := s.outbox.Pending[0]
+		err := s.replay(ctx, entry)
+
+		var conflict *conflictError
+		switch {
+		case err == nil, IsWarning(err):
+			// Replayed; a change whose history entry couldn't be recorded was still applied
+		case errors.As(err, &conflict):
+			s.outbox.Conflicts = append(s.outbox.Conflicts, SyncConflict{Entry: entry, Reason: conflict.reason, Time: time.Now().UTC()})
+		case s.wentOffline(err):
+			return err
+		default:
+			// The server rejected the change for another reason; keep it for review too
+			s.outbox.Conflicts = append(s.outbox.Conflicts, SyncConflict{Entry: entry, Reason: err.Error(), Time: time.Now().UTC()})
+		}
+
+		s.outbox.Pending = s.outbox.Pending[1:]
+		if err := s.saveOutbox(); err != nil {
+			return err
+		}
+	}
+
+	s.retryAt = time.Time{}
+	s.cause = nil
+	return nil
+}
+
+// replay applies one queued change to the remote store after checking that the
+// course on the server is still the one the change was made to. Updates and deletes
+// are written against the revision that was checked, so a change made on the server
+// in between is reported as a conflict too.
+func (s *OfflineStore) replay(ctx context.Context, e OutboxEntry) error {
+	uni, id := e.University, e.Course.ID.Hex()
+
+	switch e.Operation {
+	case OpAdd:
+		_, err := s.remote.AddCourse(ctx, uni, e.Course)
+		if errors.Is(err, ErrValidation) {
+			// A previous attempt may have reached the server before the connection dropped
+			current, getErr := s.remote.GetCourse(ctx, uni, id)
+			if getErr == nil && len(fieldDifferences(e.Course, current)) == 0 {
+				return nil
+			}
+			return &conflictError{reason: err.Error()}
+		}
+		return err
+
+	case OpUpdate:
+		current, err := s.remote.GetCourse(ctx, uni, id)
+		if errors.Is(err, ErrNotFound) {
+			return &conflictError{reason: "the course was deleted on the server"}
+		}
+		if err != nil {
+			return err
+		}
+		for _, field := range courseFields {
+			value, ok := e.Updates[field]
+			if !ok {
+				continue
+			}
+			// Compare typed values, so "8.50" matches a stored 8.5
+			wanted, err := parseFieldValue(field, value)
+			if err != nil {
+				return err
+			}
+			base, _ := e.Course.Value(field)
+			now, _ := current.Value(field)
+			if now != base && now != wanted {
+				return &conflictError{reason: fmt.Sprintf("%s is %v on the server (was %v when edited offline)", field, now, base)}
+			}
+		}
+		// Sealed grades can only be compared as ciphertext, which differs for every write
+		sealed, stored := e.Updates["SealedGrade"]
+		if stored && current.SealedGrade != e.Course.SealedGrade && current.SealedGrade != sealed {
+			return &conflictError{reason: "the grade was changed on the server"}
+		}
+		return revisionConflict(writeUpdates(ctx, s.remote, uni, id, current.Revision, e.Updates, stored))
+
+	case OpDelete:
+		current, err := s.remote.GetCourse(ctx, uni, id)
+		if errors.Is(err, ErrNotFound) {
+			if _, trashErr := ResolveTrashedCourse(ctx, s.remote, uni, id); trashErr == nil {
+				return nil
+			}
+			return &conflictError{reason: "the course no longer exists on the server"}
+		}
+		if err != nil {
+			return err
+		}
+		if diff := fieldDifferences(e.Course, current); len(diff) > 0 {
+			return &conflictError{reason: "the course was changed on the server (" + strings.Join(diff, ", ") + ")"}
+		}
+		return revisionConflict(s.remote.DeleteCourse(ctx, uni, id, current.Revision))
+
+	case OpRestore:
+		err := s.remote.RestoreCourse(ctx, uni, id)
+		if errors.Is(err, ErrNotFound) {
+			if _, getErr := s.remote.GetCourse(ctx, uni, id); getErr == nil {
+				return nil
+			}
+			return &conflictError{reason: "the course was purged on the server"}
+		}
+		return err
+
+	default:
+		return &conflictError{reason: fmt.Sprintf("unknown operation %q", e.Operation)}
+	}
+}
+
+// revisionConflict turns a *ConflictError of a replayed change into a sync conflict.
+func revisionConflict(err error) error {
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		return &conflictError{reason: "the course was changed on the server while syncing"}
+	}
+	return err
+}
+
+// fieldDifferences describes the fields whose values differ between two states of a course,
+// e.g. "Grade: 7 → 9".
+func fieldDifferences(before, after Course) []string {
+	var diff []string
+	for _, field := range courseFields {
+		a, _ := before.Value(field)
+		b, _ := after.Value(field)
+		if a != b {
+			diff = append(diff, fmt.Sprintf("%s: %v → %v", field, a, b))
+		}
+	}
+	if before.SealedGrade != after.SealedGrade {
+		diff = append(diff, "encrypted Grade")
+	}
+	return diff
+}
+
+// saveOutbox writes the outbox file. Callers hold s.mu.
+func (s *OfflineStore) saveOutbox() error {
+	data, err := json.MarshalIndent(s.outbox, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox: %w", err)
+	}
+	if err := writeFileAtomic(s.outboxPath, data); err != nil {
+		return fmt.Errorf("failed to save outbox: %w", err)
+	}
+	return nil
+}
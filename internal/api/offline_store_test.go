@@ -0,0 +1,149 @@
+package api
+
+import (
+	// Standard library imports
+	"context"       // Contexts of the store calls
+	"fmt"           // Simulated outage errors
+	"path/filepath" // Cache file location
+	"testing"       // Test framework
+)
+
+// flakyStore is a MemoryStore that fails every call with ErrUnavailable while down.
+type flakyStore struct {
+	*MemoryStore
+	// down makes every call fail as if the server were unreachable
+	down bool
+}
+
+// outage returns the error of an unreachable server while the store is down.
+func (s *flakyStore) outage() error {
+	if s.down {
+		return fmt.Errorf("server down: %w", ErrUnavailable)
+	}
+	return nil
+}
+
+func (s *flakyStore) GetAllCourses(ctx context.Context, uni string) ([]Course, error) {
+	if err := s.outage(); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.GetAllCourses(ctx, uni)
+}
+
+func (s *flakyStore) GetCourse(ctx context.Context, uni, id string) (Course, error) {
+	if err := s.outage(); err != nil {
+		return Course{}, err
+	}
+	return s.MemoryStore.GetCourse(ctx, uni, id)
+}
+
+func (s *flakyStore) UpdateCourse(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	if err := s.outage(); err != nil {
+		return err
+	}
+	return s.MemoryStore.UpdateCourse(ctx, uni, id, revision, updates)
+}
+
+func (s *flakyStore) SetEncryptionSalt(ctx context.Context, salt []byte, replace bool) ([]byte, error) {
+	if err := s.outage(); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.SetEncryptionSalt(ctx, salt, replace)
+}
+
+// TestOfflineReplayConflicts edits a course while the server is down, changes it on the
+// server in the meantime, and checks whether replaying the edit is reported as a conflict.
+func TestOfflineReplayConflicts(t *testing.T) {
+	const uni = "TU/e"
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// offline is the edit made while the server is down
+		offline map[string]string
+		// server is the edit made on the server in the meantime, if any
+		server map[string]string
+		// conflict is true if the replay must be reported as a conflict
+		conflict bool
+		// want is the course on the server after syncing
+		want Course
+	}{
+		{
+			name:    "unchanged on server",
+			offline: map[string]string{"Grade": "8"},
+			want:    Course{Name: "Calculus", Year: 1, Grade: 8, ECTS: 5},
+		},
+		{
+			name:    "same grade written differently",
+			offline: map[string]string{"Grade": "8.50"},
+			server:  map[string]string{"Grade": "8.5"},
+			want:    Course{Name: "Calculus", Year: 1, Grade: 8.5, ECTS: 5},
+		},
+		{
+			name:    "same year with leading zero",
+			offline: map[string]string{"Year": "02"},
+			server:  map[string]string{"Year": "2"},
+			want:    Course{Name: "Calculus", Year: 2, Grade: 7, ECTS: 5},
+		},
+		{
+			name:     "other grade on server",
+			offline:  map[string]string{"Grade": "8"},
+			server:   map[string]string{"Grade": "9"},
+			conflict: true,
+			want:     Course{Name: "Calculus", Year: 1, Grade: 9, ECTS: 5},
+		},
+		{
+			name:    "other field on server",
+			offline: map[string]string{"Grade": "8"},
+			server:  map[string]string{"ECTS": "6"},
+			want:    Course{Name: "Calculus", Year: 1, Grade: 8, ECTS: 6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := &flakyStore{MemoryStore: NewMemoryStore()}
+			id, err := remote.AddCourse(ctx, uni, Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := NewOfflineStore(remote, filepath.Join(t.TempDir(), "offline-cache.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Fill the cache while online
+			if _, err := s.GetAllCourses(ctx, uni); err != nil {
+				t.Fatal(err)
+			}
+
+			remote.down = true
+			if err := s.UpdateCourse(ctx, uni, id, AnyRevision, tt.offline); err != nil {
+				t.Fatalf("offline edit: %v", err)
+			}
+			remote.down = false
+			if tt.server != nil {
+				if err := remote.UpdateCourse(ctx, uni, id, AnyRevision, tt.server); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := s.Sync(ctx); err != nil {
+				t.Fatalf("Sync: %v", err)
+			}
+			status := s.Status()
+			if len(status.Pending) != 0 {
+				t.Errorf("pending changes = %d, want 0", len(status.Pending))
+			}
+			if got := len(status.Conflicts) > 0; got != tt.conflict {
+				t.Errorf("conflict = %v, want %v (conflicts: %v)", got, tt.conflict, status.Conflicts)
+			}
+
+			got, err := remote.GetCourse(ctx, uni, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := fieldDifferences(tt.want, got); len(diff) > 0 {
+				t.Errorf("course on server differs: %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	// Standard library imports
+	"errors"  // Error inspection
+	"slices"  // Comparison of course names
+	"testing" // Test framework
+	"time"    // Deletion time of the trashed course
+
+	// Third-party packages
+	"go.mongodb.org/mongo-driver/v2/bson" // Course IDs
+)
+
+// TestCourseQueryValidate checks which queries are rejected, and how valid ones are normalized.
+func TestCourseQueryValidate(t *testing.T) {
+	grade := func(g float64) *float64 { return &g }
+
+	tests := []struct {
+		name  string
+		query CourseQuery
+		// wantErr is true if the query must be rejected with ErrValidation
+		wantErr bool
+		// wantSort and wantLimit are the sort key and limit after validation
+		wantSort  string
+		wantLimit int
+	}{
+		{name: "empty", wantLimit: DefaultPageSize},
+		{name: "single year", query: CourseQuery{MinYear: 2, MaxYear: 2}, wantLimit: DefaultPageSize},
+		{name: "negative year", query: CourseQuery{MinYear: -1}, wantErr: true},
+		{name: "years reversed", query: CourseQuery{MinYear: 3, MaxYear: 1}, wantErr: true},
+		{name: "only maximum year", query: CourseQuery{MaxYear: 1}, wantLimit: DefaultPageSize},
+		{name: "grades reversed", query: CourseQuery{MinGrade: grade(8), MaxGrade: grade(6)}, wantErr: true},
+		{name: "equal grades", query: CourseQuery{MinGrade: grade(7), MaxGrade: grade(7)}, wantLimit: DefaultPageSize},
+		{name: "negative offset", query: CourseQuery{Offset: -1}, wantErr: true},
+		{name: "negative limit", query: CourseQuery{Limit: -1}, wantErr: true},
+		{name: "largest limit", query: CourseQuery{Limit: MaxPageSize}, wantLimit: MaxPageSize},
+		{name: "limit too large", query: CourseQuery{Limit: MaxPageSize + 1}, wantErr: true},
+		{name: "sort key in any case", query: CourseQuery{Sort: "grade"}, wantSort: "Grade", wantLimit: DefaultPageSize},
+		{name: "sort by computed column", query: CourseQuery{Sort: "Points"}, wantSort: "Points", wantLimit: DefaultPageSize},
+		{name: "unknown sort key", query: CourseQuery{Sort: "Teacher"}, wantErr: true},
+		{name: "sort by ID", query: CourseQuery{Sort: "ID"}, wantErr: true},
+		{name: "sort by deletion time", query: CourseQuery{Sort: "Deleted"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			err := q.Validate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrValidation) {
+					t.Errorf("Validate() = %v, want ErrValidation", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() = %v", err)
+			}
+			if q.Sort != tt.wantSort || q.Limit != tt.wantLimit {
+				t.Errorf("sort %q and limit %d, want %q and %d", q.Sort, q.Limit, tt.wantSort, tt.wantLimit)
+			}
+		})
+	}
+}
+
+// TestQueryCourses checks the filters, the order and the paging of QueryCourses.
+func TestQueryCourses(t *testing.T) {
+	grade := func(g float64) *float64 { return &g }
+	deleted := time.Now()
+	// IDs are generated in increasing order, so ties are broken in the order of this list,
+	// also when sorting in descending order
+	courses := []Course{
+		{ID: bson.NewObjectID(), Name: "Calculus", Year: 1, Grade: 7, ECTS: 5},
+		{ID: bson.NewObjectID(), Name: "Algebra", Year: 1, Grade: 8, ECTS: 5},
+		{ID: bson.NewObjectID(), Name: "Databases", Year: 2, Grade: 6.5, ECTS: 5},
+		{ID: bson.NewObjectID(), Name: "Compilers", Year: 3, Grade: 8, ECTS: 10},
+		{ID: bson.NewObjectID(), Name: "Trashed calculus", Year: 1, Grade: 9, ECTS: 5, DeletedAt: &deleted},
+	}
+
+	tests := []struct {
+		name  string
+		query CourseQuery
+		// want are the names of the courses on the page, in order
+		want []string
+		// wantTotal and wantECTS are the totals of every matching course
+		wantTotal int
+		wantECTS  float64
+		// wantNext is the offset of the next page, -1 on the last page
+		wantNext int
+	}{
+		{
+			name:      "everything by year and name",
+			want:      []string{"Algebra", "Calculus", "Databases", "Compilers"},
+			wantTotal: 4, wantECTS: 25, wantNext: -1,
+		},
+		{
+			name:      "year range",
+			query:     CourseQuery{MinYear: 2, MaxYear: 3},
+			want:      []string{"Databases", "Compilers"},
+			wantTotal: 2, wantECTS: 15, wantNext: -1,
+		},
+		{
+			name:      "grade range",
+			query:     CourseQuery{MinGrade: grade(7), MaxGrade: grade(7.5)},
+			want:      []string{"Calculus"},
+			wantTotal: 1, wantECTS: 5, wantNext: -1,
+		},
+		{
+			name:      "search ignores case and the trash",
+			query:     CourseQuery{Search: "CALC"},
+			want:      []string{"Calculus"},
+			wantTotal: 1, wantECTS: 5, wantNext: -1,
+		},
+		{
+			name:      "sort with ties broken by ID",
+			query:     CourseQuery{Sort: "Grade", Descending: true},
+			want:      []string{"Algebra", "Compilers", "Calculus", "Databases"},
+			wantTotal: 4, wantECTS: 25, wantNext: -1,
+		},
+		{
+			name:      "first page",
+			query:     CourseQuery{Limit: 3},
+			want:      []string{"Algebra", "Calculus", "Databases"},
+			wantTotal: 4, wantECTS: 25, wantNext: 3,
+		},
+		{
+			name:      "last page",
+			query:     CourseQuery{Offset: 3, Limit: 3},
+			want:      []string{"Compilers"},
+			wantTotal: 4, wantECTS: 25, wantNext: -1,
+		},
+		{
+			name:      "offset past the end",
+			query:     CourseQuery{Offset: 10},
+			want:      []string{},
+			wantTotal: 4, wantECTS: 25, wantNext: -1,
+		},
+		{
+			name:      "no match",
+			query:     CourseQuery{Search: "Physics"},
+			want:      []string{},
+			wantTotal: 0, wantECTS: 0, wantNext: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			if err := q.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			page := QueryCourses("TU/e", courses, q)
+
+			names := []string{}
+			for _, c := range page.Courses {
+				names = append(names, c.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("courses = %v, want %v", names, tt.want)
+			}
+			if page.Total != tt.wantTotal || page.TotalECTS != tt.wantECTS {
+				t.Errorf("totals = %d courses and %v ECTS, want %d and %v", page.Total, page.TotalECTS, tt.wantTotal, tt.wantECTS)
+			}
+			next := -1
+			if page.NextOffset != nil {
+				next = *page.NextOffset
+			}
+			if next != tt.wantNext {
+				t.Errorf("next offset = %d, want %d", next, tt.wantNext)
+			}
+		})
+	}
+}
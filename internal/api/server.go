@@ -4,30 +4,67 @@ package api
 
 import (
 	// Standard library imports
+	"context"       // Server shutdown
 	"encoding/json" // JSON encoding/decoding
+	"errors"        // Conflict detection
 	"fmt"           // Formatted I/O
+	"net"           // Listeners
 	"net/http"      // HTTP server and handlers
+	"net/url"       // Course URLs
+	"slices"        // Slice search helpers
+	"strconv"       // Revision parsing
+	"strings"       // Path and header parsing
+	"time"          // Server timeouts
 
-	// MongoDB driver
-	"go.mongodb.org/mongo-driver/v2/mongo"
+	// Third-party packages
+	"go.mongodb.org/mongo-driver/v2/bson" // ObjectID type
+
+	// Internal packages
+	"UniGrades/internal/university" // University data
 )
 
-// mongoClient is a module-level variable holding the MongoDB client connection.
-// It is initialized via InitServer() and used by HTTP handlers.
-var mongoClient *mongo.Client
+// courseStore is a module-level variable holding the store used by HTTP handlers.
+// It is initialized via InitServer().
+var courseStore CourseStore
+
+// courseHistory is a module-level variable holding the change history served by /history.
+// It is initialized via InitServer().
+var courseHistory HistoryStore
+
+// apiTokens is a module-level variable holding the API tokens the server accepts.
+// It is initialized via InitServer(); nil serves every request without a token.
+var apiTokens TokenStore
 
-// InitServer initializes the HTTP server with the provided MongoDB client.
+// InitServer initializes the HTTP server with the provided course, history and token stores.
 // This must be called before starting the server to ensure database operations work.
-func InitServer(client *mongo.Client) {
-	mongoClient = client
+// Every request must then carry one of the tokens; pass nil tokens only to serve without authentication.
+func InitServer(store CourseStore, history HistoryStore, tokens TokenStore) {
+	courseStore = store
+	courseHistory = history
+	apiTokens = tokens
+}
+
+// universityParam returns the university selected by the "university" query parameter.
+// Requests without the parameter use the first known university (TU/e).
+// Returns false if the parameter names an unknown university.
+func universityParam(r *http.Request) (string, bool) {
+	names := university.Names()
+	uni := r.URL.Query().Get("university")
+	if uni == "" {
+		return names[0], true
+	}
+	return uni, slices.Contains(names, uni)
 }
 
 // handleCreateCourse handles HTTP POST requests to /courses for creating new courses.
-// Expects a JSON body with course information and returns the created course's ID.
+// Expects a JSON body with course information and returns the created course's ID,
+// with its URL in the Location header. The course is added to the university given by
+// the "university" query parameter, and validated like the /add command.
 func handleCreateCourse(w http.ResponseWriter, r *http.Request) {
-	// Check that the request method is POST
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// Determine which university the course belongs to
+	uni, ok := universityParam(r)
+	if !ok {
+		http.Error(w, "Unknown university", http.StatusBadRequest)
 		return
 	}
 
@@ -38,15 +75,29 @@ func handleCreateCourse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// IDs, revisions and stored state are assigned by the store, never chosen by the client
+	course.ID = bson.ObjectID{}
+	course.Revision = 0
+	course.SealedGrade = ""
+	course.DeletedAt = nil
+
 	// Add the course to the database
-	id, err := AddCourse(mongoClient, course)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to add course: %v", err), http.StatusInternalServerError)
+	id, err := courseStore.AddCourse(r.Context(), uni, course)
+	if IsWarning(err) {
+		// The course was added, only its history entry is missing
+		w.Header().Set("Warning", fmt.Sprintf("199 - %q", err.Error()))
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to add course: %v", err), StatusCode(err))
 		return
 	}
 
-	// Return a JSON response with the new course ID
+	// Return a JSON response with the new course ID, tagged with the revision that was
+	// actually stored; the ETag is left out if the course can't be read back
 	w.Header().Set("Content-Type", "application/json")
+	if created, err := courseStore.GetCourse(r.Context(), uni, id); err == nil {
+		w.Header().Set("ETag", revisionETag(created.Revision))
+	}
+	w.Header().Set("Location", courseURL(uni, id))
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{
 		"id":      id,
@@ -54,32 +105,531 @@ func handleCreateCourse(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleGetCourses handles HTTP GET requests to /courses for retrieving all courses.
+// parseCourseQuery reads the filtering, sorting and paging query parameters of GET /courses:
+// year, minYear, maxYear, minGrade, maxGrade, search, sort, order (asc or desc), limit and offset.
+// Returns an error wrapping ErrValidation naming the first invalid parameter.
+func parseCourseQuery(r *http.Request) (CourseQuery, error) {
+	params := r.URL.Query()
+	var q CourseQuery
+
+	// Whole-number parameters must be at least their minimum
+	ints := []struct {
+		name string
+		min  int
+		dst  *int
+	}{
+		{"minYear", 1, &q.MinYear}, {"maxYear", 1, &q.MaxYear},
+		{"limit", 1, &q.Limit}, {"offset", 0, &q.Offset},
+	}
+	for _, p := range ints {
+		if value := params.Get(p.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < p.min {
+				return q, fmt.Errorf("%w: %s must be a whole number of at least %d", ErrValidation, p.name, p.min)
+			}
+			*p.dst = n
+		}
+	}
+	if params.Get("year") != "" {
+		year, ok := yearParam(r)
+		if !ok || q.MinYear != 0 || q.MaxYear != 0 {
+			return q, fmt.Errorf("%w: year must be a year of at least 1, and not combined with minYear or maxYear", ErrValidation)
+		}
+		q.MinYear, q.MaxYear = year, year
+	}
+
+	grades := []struct {
+		name string
+		dst  **float64
+	}{
+		{"minGrade", &q.MinGrade}, {"maxGrade", &q.MaxGrade},
+	}
+	for _, p := range grades {
+		if value := params.Get(p.name); value != "" {
+			grade, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return q, fmt.Errorf("%w: %s must be a number", ErrValidation, p.name)
+			}
+			*p.dst = &grade
+		}
+	}
+
+	q.Search = params.Get("search")
+	q.Sort = params.Get("sort")
+	switch strings.ToLower(params.Get("order")) {
+	case "", "asc":
+	case "desc":
+		q.Descending = true
+	default:
+		return q, fmt.Errorf("%w: order must be asc or desc", ErrValidation)
+	}
+	return q, q.Validate()
+}
+
+// handleGetCourses handles HTTP GET requests to /courses for listing the courses of the
+// university given by the "university" query parameter. The courses can be filtered,
+// sorted and paged with the parameters read by parseCourseQuery, e.g.
+// "/courses?minYear=2&sort=Grade&order=desc&limit=10"; the response is a CoursePage
+// with the number and credits of every matching course.
 func handleGetCourses(w http.ResponseWriter, r *http.Request) {
-	// Check that the request method is GET
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// Determine which university's courses to list
+	uni, ok := universityParam(r)
+	if !ok {
+		http.Error(w, "Unknown university", http.StatusBadRequest)
+		return
+	}
+	q, err := parseCourseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), StatusCode(err))
+		return
+	}
+
+	// Retrieve all courses of the university from the database
+	courses, err := courseStore.GetAllCourses(r.Context(), uni)
+	if IsWarning(err) {
+		// Serve the valid courses, but report skipped documents or cached data to the client
+		w.Header().Set("Warning", fmt.Sprintf("199 - %q", err.Error()))
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get courses: %v", err), StatusCode(err))
+		return
+	}
+
+	// Return the requested page as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(QueryCourses(uni, courses, q))
+}
+
+// handleGetHistory handles HTTP GET requests to /history for browsing the change history
+// of the university given by the "university" query parameter, newest first.
+// The optional "course" query parameter narrows the history to one course, given by
+// name or ID; deleted courses can be looked up too.
+func handleGetHistory(w http.ResponseWriter, r *http.Request) {
+	// Determine which university's history to list
+	uni, ok := universityParam(r)
+	if !ok {
+		http.Error(w, "Unknown university", http.StatusBadRequest)
+		return
+	}
+
+	// Retrieve the history of the university and keep the requested course's entries
+	entries, err := courseHistory.GetHistory(r.Context(), uni)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get history: %v", err), StatusCode(err))
+		return
+	}
+	entries = FilterHistory(entries, r.URL.Query().Get("course"))
+	if entries == nil {
+		entries = []HistoryEntry{}
+	}
+
+	// Return the entries as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// revisionETag returns the entity tag of a course revision, e.g. "3".
+func revisionETag(revision int64) string {
+	return strconv.Quote(strconv.FormatInt(revision, 10))
+}
+
+// ifMatchRevision returns the course revision required by the If-Match header.
+// Requests without the header, or with "*", change the course whatever its revision.
+// Returns false if the header holds no revision this API hands out, so nothing can match.
+func ifMatchRevision(r *http.Request) (int64, bool) {
+	tag := strings.TrimSpace(r.Header.Get("If-Match"))
+	if tag == "" || tag == "*" {
+		return AnyRevision, true
+	}
+	unquoted, err := strconv.Unquote(strings.TrimPrefix(tag, "W/"))
+	if err != nil {
+		return 0, false
+	}
+	revision, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || revision < 1 {
+		return 0, false
+	}
+	return revision, true
+}
+
+// writeCourseError writes the error of a single-course request. A revision conflict
+// answers 412 Precondition Failed with the course's current ETag, so the client can
+// fetch it again and decide whether to retry.
+func writeCourseError(w http.ResponseWriter, prefix string, err error) {
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		w.Header().Set("ETag", revisionETag(conflict.Current.Revision))
+		http.Error(w, fmt.Sprintf("%s: %v", prefix, err), http.StatusPreconditionFailed)
 		return
 	}
+	http.Error(w, fmt.Sprintf("%s: %v", prefix, err), StatusCode(err))
+}
 
-	// Retrieve all courses from the database
-	courses := GetAllCourses(mongoClient)
+// courseURL returns the URL of a course, e.g. "/courses/665f1c...?university=TU%2Fe".
+func courseURL(uni, id string) string {
+	return "/courses/" + id + "?" + url.Values{"university": {uni}}.Encode()
+}
 
-	// Return the courses as JSON
+// writeCourse writes a course as JSON with its revision as ETag.
+func writeCourse(w http.ResponseWriter, course Course) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(courses)
+	w.Header().Set("ETag", revisionETag(course.Revision))
+	json.NewEncoder(w).Encode(course)
+}
+
+// updateCourse applies updates to the course named by the {id} path parameter, honoring
+// If-Match, and answers with the updated course. PATCH and PUT share it, so both run on
+// the same validation as UpdateCourse and the /edit command.
+func updateCourse(w http.ResponseWriter, r *http.Request, uni string, updates map[string]string) {
+	id := r.PathValue("id")
+	revision, ok := ifMatchRevision(r)
+	if !ok {
+		http.Error(w, "If-Match does not match the course", http.StatusPreconditionFailed)
+		return
+	}
+
+	err := courseStore.UpdateCourse(r.Context(), uni, id, revision, updates)
+	if IsWarning(err) {
+		w.Header().Set("Warning", fmt.Sprintf("199 - %q", err.Error()))
+	} else if err != nil {
+		writeCourseError(w, "Failed to update course", err)
+		return
+	}
+	course, err := courseStore.GetCourse(r.Context(), uni, id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get course: %v", err), StatusCode(err))
+		return
+	}
+	writeCourse(w, course)
 }
 
-// StartServer initializes and starts the HTTP server on the specified port.
-// Registers HTTP handlers for course operations and handles errors.
-func StartServer(port string) {
-	// Register HTTP handlers for course endpoints
-	http.HandleFunc("/courses", handleCreateCourse)
-	http.HandleFunc("/courses", handleGetCourses)
+// handleGetCourse handles HTTP GET requests to /courses/{id} and returns the course
+// of the university given by the "university" query parameter, with its revision as ETag.
+func handleGetCourse(w http.ResponseWriter, r *http.Request) {
+	// Determine which university the course belongs to
+	uni, ok := universityParam(r)
+	if !ok {
+		http.Error(w, "Unknown university", http.StatusBadRequest)
+		return
+	}
 
-	// Start the HTTP server
-	fmt.Printf("Server starting on http://localhost:%s\n", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		fmt.Println("Server error:", err)
+	course, err := courseStore.GetCourse(r.Context(), uni, r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get course: %v", err), StatusCode(err))
+		return
+	}
+	writeCourse(w, course)
+}
+
+// handlePatchCourse handles HTTP PATCH requests to /courses/{id}, which set the fields of
+// a JSON object such as {"Grade": 8.5, "ECTS": 5} and leave the others as they are.
+// With If-Match, nothing is written when the course has another revision by now and the
+// response is 412 Precondition Failed.
+func handlePatchCourse(w http.ResponseWriter, r *http.Request) {
+	// Determine which university the course belongs to
+	uni, ok := universityParam(r)
+	if !ok {
+		http.Error(w, "Unknown university", http.StatusBadRequest)
+		return
+	}
+
+	// Field values may be given as JSON strings or numbers; the store validates them
+	var fields map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil || len(fields) == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	updates := make(map[string]string, len(fields))
+	for field, value := range fields {
+		updates[field] = formatFieldValue(value)
+	}
+
+	updateCourse(w, r, uni, updates)
+}
+
+// formatFieldValue converts a decoded JSON field value to the string UpdateCourse parses.
+// Numbers are written out in full, since fmt would print 1000000 as "1e+06", which isn't
+// a valid Year; anything else that isn't a string is left for the store to reject.
+func formatFieldValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// handlePutCourse handles HTTP PUT requests to /courses/{id}, which replace every editable
+// field of the course with those of a JSON course such as
+// {"Name": "Applied_Math", "Year": 1, "Grade": 8.5, "ECTS": 5}. The course must be complete
+// and valid, as for POST /courses; its ID, revision and deletion time are ignored.
+// If-Match is honored as for PATCH.
+func handlePutCourse(w http.ResponseWriter, r *http.Request) {
+	// Determine which university the course belongs to
+	uni, ok := universityParam(r)
+	if !ok {
+		http.Error(w, "Unknown university", http.StatusBadRequest)
+		return
+	}
+
+	var course Course
+	if err := json.NewDecoder(r.Body).Decode(&course); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateCourse(course); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update course: %v", err), StatusCode(err))
+		return
+	}
+
+	updateCourse(w, r, uni, map[string]string{
+		"Name":  course.Name,
+		"Year":  strconv.Itoa(course.Year),
+		"Grade": strconv.FormatFloat(course.Grade, 'f', -1, 64),
+		"ECTS":  strconv.FormatFloat(course.ECTS, 'f', -1, 64),
+	})
+}
+
+// handleDeleteCourse handles HTTP DELETE requests to /courses/{id}, which move the course
+// to the trash. If-Match is honored as for PATCH.
+func handleDeleteCourse(w http.ResponseWriter, r *http.Request) {
+	// Determine which university the course belongs to
+	uni, ok := universityParam(r)
+	if !ok {
+		http.Error(w, "Unknown university", http.StatusBadRequest)
+		return
+	}
+
+	revision, ok := ifMatchRevision(r)
+	if !ok {
+		http.Error(w, "If-Match does not match the course", http.StatusPreconditionFailed)
+		return
+	}
+	err := courseStore.DeleteCourse(r.Context(), uni, r.PathValue("id"), revision)
+	if IsWarning(err) {
+		w.Header().Set("Warning", fmt.Sprintf("199 - %q", err.Error()))
+	} else if err != nil {
+		writeCourseError(w, "Failed to delete course", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// yearParam returns the year selected by the "year" query parameter, or 0 if there is none.
+// Returns false if the parameter is not a year of at least 1.
+func yearParam(r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("year")
+	if value == "" {
+		return 0, true
+	}
+	year, err := strconv.Atoi(value)
+	return year, err == nil && year >= 1
+}
+
+// loadStats returns the statistics of the university and year selected by the query
+// parameters, computed by the store. It writes the error response and returns false if
+// a parameter is invalid or the statistics can't be computed; statistics served with a
+// warning (e.g. from the offline cache) report it in the Warning header.
+func loadStats(w http.ResponseWriter, r *http.Request) (CourseStats, int, bool) {
+	uni, ok := universityParam(r)
+	if !ok {
+		http.Error(w, "Unknown university", http.StatusBadRequest)
+		return CourseStats{}, 0, false
+	}
+	year, ok := yearParam(r)
+	if !ok {
+		http.Error(w, "Invalid year", http.StatusBadRequest)
+		return CourseStats{}, 0, false
+	}
+
+	stats, err := courseStore.GetStats(r.Context(), uni)
+	if IsWarning(err) {
+		w.Header().Set("Warning", fmt.Sprintf("199 - %q", err.Error()))
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get statistics: %v", err), StatusCode(err))
+		return CourseStats{}, 0, false
+	}
+	return stats, year, true
+}
+
+// handleStatsSummary handles HTTP GET requests to /stats/summary, which return the
+// course count, average and ECTS-weighted average grade and total ECTS of a university,
+// the figures of the TUI's average grades table. The optional "year" query parameter
+// narrows them to one year.
+func handleStatsSummary(w http.ResponseWriter, r *http.Request) {
+	stats, year, ok := loadStats(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats.Summary(year))
+}
+
+// handleStatsYears handles HTTP GET requests to /stats/years, which return the statistics
+// of every year a course was taken, the figures of the TUI's per-year charts. The optional
+// "year" query parameter keeps only that year, leaving the list empty if no course was taken then.
+func handleStatsYears(w http.ResponseWriter, r *http.Request) {
+	stats, year, ok := loadStats(w, r)
+	if !ok {
+		return
+	}
+	years := stats.Years
+	if year != 0 {
+		years = slices.DeleteFunc(years, func(y YearStats) bool { return y.Year != year })
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		University string      `json:"university"`
+		Years      []YearStats `json:"years"`
+	}{stats.University, years})
+}
+
+// handleStatsECTSProgress handles HTTP GET requests to /stats/ects-progress, which return
+// the credits earned toward the degree, per year and in total, the figures of the TUI's
+// ECTS bar. The optional "year" query parameter counts the credits up to and including that year.
+func handleStatsECTSProgress(w http.ResponseWriter, r *http.Request) {
+	stats, year, ok := loadStats(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats.Progress(year))
+}
+
+// bearerToken returns the token sent in the "Authorization: Bearer <token>" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// requireToken is middleware that only lets requests through that carry a stored API token
+// allowing their method. Requests without a valid token are answered with 401 Unauthorized,
+// and requests the token's scope doesn't allow (e.g. a DELETE with a read-only token)
+// with 403 Forbidden.
+func requireToken(tokens TokenStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="UniGrades"`)
+			http.Error(w, "Missing API token: send it as \"Authorization: Bearer <token>\"", http.StatusUnauthorized)
+			return
+		}
+
+		token, err := VerifyToken(r.Context(), tokens, secret)
+		if errors.Is(err, ErrUnauthorized) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="UniGrades", error="invalid_token"`)
+			http.Error(w, fmt.Sprintf("Invalid API token: %v", err), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to check API token: %v", err), StatusCode(err))
+			return
+		}
+
+		if !token.Allows(r.Method) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="UniGrades", error="insufficient_scope"`)
+			http.Error(w, fmt.Sprintf("The API token '%s' is %s-only", token.Name, token.Scope), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// NewHandler returns the HTTP handler serving the API. Routes are matched on method and
+// path, so other methods are answered with 405 Method Not Allowed and an Allow header:
+//
+//	GET    /courses              lists, filters, sorts and pages the courses of a university
+//	POST   /courses              adds a course
+//	GET    /courses/{id}         returns a course
+//	PATCH  /courses/{id}         changes some fields of a course
+//	PUT    /courses/{id}         replaces all fields of a course
+//	DELETE /courses/{id}         moves a course to the trash
+//	GET    /history              lists the change history
+//	GET    /stats/summary        returns the average grades and total ECTS
+//	GET    /stats/years          returns the statistics per year
+//	GET    /stats/ects-progress  returns the progress toward the degree
+//
+// Every route takes the university as the "university" query parameter (default TU/e);
+// the statistics also take an optional "year". Unless InitServer was given no token store,
+// every request needs an API token: read-only tokens may only GET.
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /courses", handleGetCourses)
+	mux.HandleFunc("POST /courses", handleCreateCourse)
+	mux.HandleFunc("GET /courses/{id}", handleGetCourse)
+	mux.HandleFunc("PATCH /courses/{id}", handlePatchCourse)
+	mux.HandleFunc("PUT /courses/{id}", handlePutCourse)
+	mux.HandleFunc("DELETE /courses/{id}", handleDeleteCourse)
+	mux.HandleFunc("GET /history", handleGetHistory)
+	mux.HandleFunc("GET /stats/summary", handleStatsSummary)
+	mux.HandleFunc("GET /stats/years", handleStatsYears)
+	mux.HandleFunc("GET /stats/ects-progress", handleStatsECTSProgress)
+
+	if apiTokens == nil {
+		return mux
+	}
+	return requireToken(apiTokens, mux)
+}
+
+// shutdownTimeout bounds how long a stopping server waits for requests in flight.
+const shutdownTimeout = 5 * time.Second
+
+// Serve serves the routes of NewHandler on the listener until ctx is canceled, then shuts
+// the server down gracefully: it stops accepting connections and waits up to five seconds
+// for requests in flight to finish. InitServer must have been called first.
+//
+// Parameters:
+//
+//	ctx: Context whose cancellation (e.g. on Ctrl+C) stops the server
+//	ln: The listener to accept connections on
+//
+// Returns:
+//
+//	nil once the server has shut down, or the error that stopped it.
+func Serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           NewHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("server error: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("failed to shut down the server: %w", err)
+		}
+		return nil
+	}
+}
+
+// StartServer listens on the given address (e.g. "localhost:8080") and serves the API
+// until ctx is canceled. See Serve.
+//
+// Parameters:
+//
+//	ctx: Context whose cancellation stops the server
+//	addr: The TCP address to listen on
+//
+// Returns:
+//
+//	nil once the server has shut down, or an error if it can't listen or fails.
+func StartServer(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
 	}
+	return Serve(ctx, ln)
 }
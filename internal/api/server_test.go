@@ -0,0 +1,37 @@
+package api
+
+import (
+	// Standard library imports
+	"encoding/json" // Decoding of PATCH bodies
+	"testing"       // Test framework
+)
+
+// TestFormatFieldValue checks how PATCH /courses/{id} turns JSON field values into updates.
+func TestFormatFieldValue(t *testing.T) {
+	tests := []struct {
+		name string
+		// body is the JSON value of the field
+		body string
+		want string
+	}{
+		{name: "string", body: `"Applied_Math"`, want: "Applied_Math"},
+		{name: "integer", body: `2`, want: "2"},
+		{name: "fraction", body: `8.5`, want: "8.5"},
+		{name: "large number", body: `1000000`, want: "1000000"},
+		{name: "exponent", body: `1e6`, want: "1000000"},
+		{name: "small number", body: `0.0000001`, want: "0.0000001"},
+		{name: "number as string", body: `"7.5"`, want: "7.5"},
+		{name: "boolean", body: `true`, want: "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.body), &value); err != nil {
+				t.Fatal(err)
+			}
+			if got := formatFieldValue(value); got != tt.want {
+				t.Errorf("formatFieldValue(%s) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"sort" // Ordering of per-year statistics
+)
+
+// DegreeECTS is the number of credits needed for the degree (180 for most bachelor's programs).
+// The TUI's ECTS bar and the /stats/ects-progress endpoint both measure progress against it.
+const DegreeECTS = 180.0
+
+// CourseStats summarizes the active courses of a university, as returned by GetStats.
+type CourseStats struct {
+	// University is the university the statistics describe
+	University string `json:"university"`
+	// Count is the number of active courses
+	Count int `json:"count"`
+	// AverageGrade is the plain mean of all grades, 0 without courses
+	AverageGrade float64 `json:"averageGrade"`
+	// WeightedAverage is the mean of all grades weighted by ECTS, 0 without credits
+	WeightedAverage float64 `json:"weightedAverage"`
+	// TotalECTS is the sum of all ECTS credits
+	TotalECTS float64 `json:"totalECTS"`
+	// Years holds the same statistics per year, in ascending year order
+	Years []YearStats `json:"years"`
+}
+
+// YearStats summarizes the active courses of a single year.
+type YearStats struct {
+	// Year is the study year the courses were taken in
+	Year int `json:"year"`
+	// Count is the number of courses taken that year
+	Count int `json:"count"`
+	// AverageGrade is the plain mean of the year's grades
+	AverageGrade float64 `json:"averageGrade"`
+	// WeightedAverage is the mean of the year's grades weighted by ECTS
+	WeightedAverage float64 `json:"weightedAverage"`
+	// TotalECTS is the sum of the year's ECTS credits
+	TotalECTS float64 `json:"totalECTS"`
+}
+
+// Year returns the statistics of the given year, or zero statistics if no course was taken that year.
+func (s CourseStats) Year(year int) YearStats {
+	for _, y := range s.Years {
+		if y.Year == year {
+			return y
+		}
+	}
+	return YearStats{Year: year}
+}
+
+// statsTotals holds the running sums every statistic is derived from. Stores fill them
+// per year, in Go or in an aggregation pipeline, so all backends share the same formulas.
+type statsTotals struct {
+	// Count is the number of courses summed
+	Count int
+	// GradeSum is the sum of the grades
+	GradeSum float64
+	// WeightedSum is the sum of every grade multiplied by its ECTS
+	WeightedSum float64
+	// ECTS is the sum of the ECTS credits
+	ECTS float64
+}
+
+// add sums another course into the totals.
+func (t *statsTotals) add(c Course) {
+	t.Count++
+	t.GradeSum += c.Grade
+	t.WeightedSum += c.Grade * c.ECTS
+	t.ECTS += c.ECTS
+}
+
+// averages returns the plain and the ECTS-weighted average grade of the totals.
+func (t statsTotals) averages() (float64, float64) {
+	var avg, weighted float64
+	if t.Count > 0 {
+		avg = t.GradeSum / float64(t.Count)
+	}
+	if t.ECTS != 0 {
+		weighted = t.WeightedSum / t.ECTS
+	}
+	return avg, weighted
+}
+
+// buildStats derives the statistics of a university from its totals per year.
+func buildStats(uni string, perYear map[int]statsTotals) CourseStats {
+	stats := CourseStats{University: uni, Years: []YearStats{}}
+	var all statsTotals
+	for year, t := range perYear {
+		avg, weighted := t.averages()
+		stats.Years = append(stats.Years, YearStats{
+			Year:            year,
+			Count:           t.Count,
+			AverageGrade:    avg,
+			WeightedAverage: weighted,
+			TotalECTS:       t.ECTS,
+		})
+		all.Count += t.Count
+		all.GradeSum += t.GradeSum
+		all.WeightedSum += t.WeightedSum
+		all.ECTS += t.ECTS
+	}
+	sort.Slice(stats.Years, func(i, j int) bool { return stats.Years[i].Year < stats.Years[j].Year })
+
+	stats.Count = all.Count
+	stats.AverageGrade, stats.WeightedAverage = all.averages()
+	stats.TotalECTS = all.ECTS
+	return stats
+}
+
+// ComputeStats computes the statistics of the given courses in Go, leaving trashed courses out.
+// Stores without an aggregation engine use it, and it gives the same results as the
+// MongoDB pipeline for the same courses.
+func ComputeStats(uni string, courses []Course) CourseStats {
+	perYear := make(map[int]statsTotals)
+	for _, c := range ActiveCourses(courses) {
+		t := perYear[c.Year]
+		t.add(c)
+		perYear[c.Year] = t
+	}
+	return buildStats(uni, perYear)
+}
+
+// StatsSummary is the overall grade and ECTS summary of a university, as shown in the TUI's
+// average grades table and ECTS bar, optionally narrowed to one year.
+type StatsSummary struct {
+	// University is the university the summary describes
+	University string `json:"university"`
+	// Year is the year the summary is narrowed to, omitted for all years
+	Year int `json:"year,omitempty"`
+	// Count is the number of active courses
+	Count int `json:"count"`
+	// AverageGrade is the plain mean of the grades
+	AverageGrade float64 `json:"averageGrade"`
+	// WeightedAverage is the mean of the grades weighted by ECTS
+	WeightedAverage float64 `json:"weightedAverage"`
+	// TotalECTS is the sum of the ECTS credits
+	TotalECTS float64 `json:"totalECTS"`
+}
+
+// Summary returns the overall summary of the statistics, or that of a single year.
+// A year of 0 summarizes all years.
+func (s CourseStats) Summary(year int) StatsSummary {
+	if year != 0 {
+		y := s.Year(year)
+		return StatsSummary{University: s.University, Year: year, Count: y.Count,
+			AverageGrade: y.AverageGrade, WeightedAverage: y.WeightedAverage, TotalECTS: y.TotalECTS}
+	}
+	return StatsSummary{University: s.University, Count: s.Count,
+		AverageGrade: s.AverageGrade, WeightedAverage: s.WeightedAverage, TotalECTS: s.TotalECTS}
+}
+
+// ECTSProgress measures the earned credits of a university against DegreeECTS.
+type ECTSProgress struct {
+	// University is the university the progress describes
+	University string `json:"university"`
+	// Through is the last year counted, omitted when every year is counted
+	Through int `json:"through,omitempty"`
+	// EarnedECTS is the sum of the credits earned
+	EarnedECTS float64 `json:"earnedECTS"`
+	// RequiredECTS is the number of credits needed for the degree
+	RequiredECTS float64 `json:"requiredECTS"`
+	// RemainingECTS is the number of credits still needed, 0 once the degree is complete
+	RemainingECTS float64 `json:"remainingECTS"`
+	// Percent is the share of the required credits earned, at most 100
+	Percent float64 `json:"percent"`
+	// Years holds the credits earned per year and in total by the end of it, in ascending year order
+	Years []YearProgress `json:"years"`
+}
+
+// YearProgress holds the credits earned in one year.
+type YearProgress struct {
+	// Year is the study year
+	Year int `json:"year"`
+	// ECTS is the sum of the credits earned that year
+	ECTS float64 `json:"ects"`
+	// CumulativeECTS is the sum of the credits earned up to and including that year
+	CumulativeECTS float64 `json:"cumulativeECTS"`
+}
+
+// Progress returns the progress toward the degree. With a year other than 0, only the
+// credits earned up to and including that year are counted.
+func (s CourseStats) Progress(through int) ECTSProgress {
+	p := ECTSProgress{University: s.University, Through: through, RequiredECTS: DegreeECTS, Years: []YearProgress{}}
+	for _, y := range s.Years {
+		if through != 0 && y.Year > through {
+			break
+		}
+		p.EarnedECTS += y.TotalECTS
+		p.Years = append(p.Years, YearProgress{Year: y.Year, ECTS: y.TotalECTS, CumulativeECTS: p.EarnedECTS})
+	}
+	p.RemainingECTS = max(DegreeECTS-p.EarnedECTS, 0)
+	p.Percent = min(p.EarnedECTS/DegreeECTS*100, 100)
+	return p
+}
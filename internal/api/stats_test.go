@@ -0,0 +1,167 @@
+package api
+
+import (
+	// Standard library imports
+	"math"    // Tolerance of float comparisons
+	"reflect" // Field tags of statsGroup
+	"slices"  // Comparison of field names
+	"testing" // Test framework
+	"time"    // Deletion time of trashed courses
+
+	// Third-party packages
+	"go.mongodb.org/mongo-driver/v2/bson" // Encoding of the simulated groups
+)
+
+// groupLikeMongo does what the $group stage of statsPipeline does on the server, after
+// the $match stage left trashed courses out: one document per year, with the counts as
+// 32-bit integers like MongoDB returns them.
+func groupLikeMongo(t *testing.T, courses []Course) []bson.Raw {
+	t.Helper()
+	var years []int
+	groups := map[int]bson.D{}
+	for _, c := range ActiveCourses(courses) {
+		g, ok := groups[c.Year]
+		if !ok {
+			years = append(years, c.Year)
+			g = bson.D{
+				{Key: "_id", Value: int32(c.Year)},
+				{Key: "count", Value: int32(0)},
+				{Key: "gradeSum", Value: 0.0},
+				{Key: "weightedSum", Value: 0.0},
+				{Key: "ects", Value: 0.0},
+			}
+		}
+		g[1].Value = g[1].Value.(int32) + 1
+		g[2].Value = g[2].Value.(float64) + c.Grade
+		g[3].Value = g[3].Value.(float64) + c.Grade*c.ECTS
+		g[4].Value = g[4].Value.(float64) + c.ECTS
+		groups[c.Year] = g
+	}
+
+	// The server returns the groups in no particular order
+	slices.Reverse(years)
+	docs := make([]bson.Raw, 0, len(years))
+	for _, year := range years {
+		doc, err := bson.Marshal(groups[year])
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// TestStatsPipelineFields checks that the $group stage of the pipeline produces exactly
+// the fields statsGroup decodes.
+func TestStatsPipelineFields(t *testing.T) {
+	var produced []string
+	for _, stage := range statsPipeline() {
+		if stage[0].Key == "$group" {
+			for _, field := range stage[0].Value.(bson.D) {
+				produced = append(produced, field.Key)
+			}
+		}
+	}
+
+	var decoded []string
+	groupType := reflect.TypeFor[statsGroup]()
+	for i := range groupType.NumField() {
+		decoded = append(decoded, groupType.Field(i).Tag.Get("bson"))
+	}
+	if !slices.Equal(produced, decoded) {
+		t.Errorf("$group produces %v, statsGroup decodes %v", produced, decoded)
+	}
+}
+
+// TestComputeStatsMatchesPipeline checks that ComputeStats and the statistics derived from
+// the pipeline's groups agree on the same courses.
+func TestComputeStatsMatchesPipeline(t *testing.T) {
+	deleted := time.Now()
+
+	tests := []struct {
+		name    string
+		courses []Course
+		// want is the expected overall and per-year statistics, without the university
+		want CourseStats
+	}{
+		{
+			name: "no courses",
+			want: CourseStats{Years: []YearStats{}},
+		},
+		{
+			name: "single year",
+			courses: []Course{
+				{Name: "Calculus", Year: 1, Grade: 6, ECTS: 5},
+				{Name: "Algebra", Year: 1, Grade: 9, ECTS: 10},
+			},
+			want: CourseStats{Count: 2, AverageGrade: 7.5, WeightedAverage: 8, TotalECTS: 15, Years: []YearStats{
+				{Year: 1, Count: 2, AverageGrade: 7.5, WeightedAverage: 8, TotalECTS: 15},
+			}},
+		},
+		{
+			name: "years in ascending order",
+			courses: []Course{
+				{Name: "Compilers", Year: 3, Grade: 8, ECTS: 5},
+				{Name: "Calculus", Year: 1, Grade: 6, ECTS: 5},
+			},
+			want: CourseStats{Count: 2, AverageGrade: 7, WeightedAverage: 7, TotalECTS: 10, Years: []YearStats{
+				{Year: 1, Count: 1, AverageGrade: 6, WeightedAverage: 6, TotalECTS: 5},
+				{Year: 3, Count: 1, AverageGrade: 8, WeightedAverage: 8, TotalECTS: 5},
+			}},
+		},
+		{
+			name: "no credits",
+			courses: []Course{
+				{Name: "Seminar", Year: 2, Grade: 7, ECTS: 0},
+			},
+			want: CourseStats{Count: 1, AverageGrade: 7, TotalECTS: 0, Years: []YearStats{
+				{Year: 2, Count: 1, AverageGrade: 7},
+			}},
+		},
+		{
+			name: "trash left out",
+			courses: []Course{
+				{Name: "Calculus", Year: 1, Grade: 6, ECTS: 5},
+				{Name: "Dropped", Year: 1, Grade: 1, ECTS: 5, DeletedAt: &deleted},
+				{Name: "Dropped later", Year: 2, Grade: 2, ECTS: 5, DeletedAt: &deleted},
+			},
+			want: CourseStats{Count: 1, AverageGrade: 6, WeightedAverage: 6, TotalECTS: 5, Years: []YearStats{
+				{Year: 1, Count: 1, AverageGrade: 6, WeightedAverage: 6, TotalECTS: 5},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var groups []statsGroup
+			for _, doc := range groupLikeMongo(t, tt.courses) {
+				var g statsGroup
+				if err := bson.Unmarshal(doc, &g); err != nil {
+					t.Fatal(err)
+				}
+				groups = append(groups, g)
+			}
+
+			want := tt.want
+			want.University = "TU/e"
+			for name, got := range map[string]CourseStats{
+				"ComputeStats":    ComputeStats("TU/e", tt.courses),
+				"statsFromGroups": statsFromGroups("TU/e", groups),
+			} {
+				if !statsEqual(got, want) {
+					t.Errorf("%s = %+v, want %+v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// statsEqual reports whether two statistics are equal, up to float rounding.
+func statsEqual(a, b CourseStats) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	yearsEqual := slices.EqualFunc(a.Years, b.Years, func(x, y YearStats) bool {
+		return x.Year == y.Year && x.Count == y.Count && near(x.AverageGrade, y.AverageGrade) &&
+			near(x.WeightedAverage, y.WeightedAverage) && near(x.TotalECTS, y.TotalECTS)
+	})
+	return a.University == b.University && a.Count == b.Count && near(a.AverageGrade, b.AverageGrade) &&
+		near(a.WeightedAverage, b.WeightedAverage) && near(a.TotalECTS, b.TotalECTS) && yearsEqual
+}
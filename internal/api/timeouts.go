@@ -0,0 +1,139 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context" // Deadlines for store operations
+	"time"    // Durations
+)
+
+// Timeouts holds the maximum duration of each store operation.
+// A zero duration disables the timeout for that operation.
+type Timeouts struct {
+	// Load bounds GetAllCourses, GetCourse, GetStats and GetTrashedCourses
+	Load time.Duration
+	// Add bounds AddCourse
+	Add time.Duration
+	// Update bounds UpdateCourse and RestoreCourse
+	Update time.Duration
+	// Delete bounds DeleteCourse and PurgeTrash
+	Delete time.Duration
+}
+
+// DefaultTimeouts returns the timeouts used when none are configured.
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Load:   10 * time.Second,
+		Add:    5 * time.Second,
+		Update: 5 * time.Second,
+		Delete: 5 * time.Second,
+	}
+}
+
+// timeoutStore is a CourseStore decorator that applies a deadline to every operation
+// before delegating to the wrapped store.
+type timeoutStore struct {
+	// store is the wrapped store that performs the operations
+	store CourseStore
+	// timeouts holds the deadline of each operation
+	timeouts Timeouts
+}
+
+// WithTimeouts wraps a store so that each operation fails with context.DeadlineExceeded
+// once its configured timeout expires. This keeps a hung database connection from
+// blocking the caller forever.
+//
+// Parameters:
+//
+//	store: The store to wrap
+//	timeouts: The maximum duration of each operation
+//
+// Returns:
+//
+//	A CourseStore applying the timeouts.
+func WithTimeouts(store CourseStore, timeouts Timeouts) CourseStore {
+	return &timeoutStore{store: store, timeouts: timeouts}
+}
+
+// Unwrap returns the wrapped store.
+func (s *timeoutStore) Unwrap() CourseStore {
+	return s.store
+}
+
+// withTimeout derives a context bounded by d, or a plain cancelable context if d is zero.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// GetAllCourses delegates to the wrapped store within the Load timeout.
+func (s *timeoutStore) GetAllCourses(ctx context.Context, uni string) ([]Course, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Load)
+	defer cancel()
+	return s.store.GetAllCourses(ctx, uni)
+}
+
+// GetCourse delegates to the wrapped store within the Load timeout.
+func (s *timeoutStore) GetCourse(ctx context.Context, uni, id string) (Course, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Load)
+	defer cancel()
+	return s.store.GetCourse(ctx, uni, id)
+}
+
+// AddCourse delegates to the wrapped store within the Add timeout.
+func (s *timeoutStore) AddCourse(ctx context.Context, uni string, course Course) (string, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Add)
+	defer cancel()
+	return s.store.AddCourse(ctx, uni, course)
+}
+
+// DeleteCourse delegates to the wrapped store within the Delete timeout.
+func (s *timeoutStore) DeleteCourse(ctx context.Context, uni, id string, revision int64) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Delete)
+	defer cancel()
+	return s.store.DeleteCourse(ctx, uni, id, revision)
+}
+
+// UpdateCourse delegates to the wrapped store within the Update timeout.
+func (s *timeoutStore) UpdateCourse(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Update)
+	defer cancel()
+	return s.store.UpdateCourse(ctx, uni, id, revision, updates)
+}
+
+// updateStored delegates to the wrapped store within the Update timeout.
+func (s *timeoutStore) updateStored(ctx context.Context, uni, id string, revision int64, updates map[string]string) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Update)
+	defer cancel()
+	return updateStored(ctx, s.store, uni, id, revision, updates)
+}
+
+// GetStats delegates to the wrapped store within the Load timeout.
+func (s *timeoutStore) GetStats(ctx context.Context, uni string) (CourseStats, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Load)
+	defer cancel()
+	return s.store.GetStats(ctx, uni)
+}
+
+// GetTrashedCourses delegates to the wrapped store within the Load timeout.
+func (s *timeoutStore) GetTrashedCourses(ctx context.Context, uni string) ([]Course, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Load)
+	defer cancel()
+	return s.store.GetTrashedCourses(ctx, uni)
+}
+
+// RestoreCourse delegates to the wrapped store within the Update timeout.
+func (s *timeoutStore) RestoreCourse(ctx context.Context, uni, id string) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Update)
+	defer cancel()
+	return s.store.RestoreCourse(ctx, uni, id)
+}
+
+// PurgeTrash delegates to the wrapped store within the Delete timeout.
+func (s *timeoutStore) PurgeTrash(ctx context.Context, uni string, before time.Time) (int, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Delete)
+	defer cancel()
+	return s.store.PurgeTrash(ctx, uni, before)
+}
@@ -0,0 +1,233 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context"         // Cancellation of operations
+	"crypto/rand"     // Random token secrets and IDs
+	"crypto/sha256"   // Hashing of token secrets
+	"encoding/base64" // Text encoding of token secrets
+	"encoding/hex"    // Text encoding of token IDs and hashes
+	"errors"          // Error inspection
+	"fmt"             // Formatted I/O package
+	"net/http"        // Request methods allowed by a scope
+	"sort"            // Ordering of listed tokens
+	"strings"         // Token prefix
+	"sync"            // Mutex guarding the in-memory tokens
+	"time"            // Creation times
+)
+
+// tokenPrefix starts every token secret, so a leaked token is easy to recognize.
+const tokenPrefix = "ugt_"
+
+// TokenScope is what an API token allows its bearer to do.
+type TokenScope string
+
+const (
+	// ScopeRead allows reading courses, history and statistics (GET and HEAD requests),
+	// e.g. for a mentor or parent following the grades
+	ScopeRead TokenScope = "read"
+	// ScopeWrite allows every request, including adding, changing and deleting courses
+	ScopeWrite TokenScope = "write"
+)
+
+// ParseScope returns the scope with the given name.
+// Returns an error wrapping ErrValidation for anything other than "read" or "write".
+func ParseScope(name string) (TokenScope, error) {
+	switch scope := TokenScope(name); scope {
+	case ScopeRead, ScopeWrite:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("%w: unknown scope %q. Valid scopes are: read, write", ErrValidation, name)
+	}
+}
+
+// APIToken is an API token as it is stored. Only the hash of its secret is kept, so the
+// stored tokens can't be used to call the API; the secret is shown once, when the token is created.
+type APIToken struct {
+	// ID identifies the token when listing and revoking it, e.g. "3f9a1c2e"
+	ID string `bson:"_id" json:"id"`
+	// Name describes who or what uses the token, e.g. "mentor"
+	Name string `bson:"Name" json:"name"`
+	// Scope is what the token allows
+	Scope TokenScope `bson:"Scope" json:"scope"`
+	// Hash is the SHA-256 hash of the token secret, e.g. "sha256:9f86d0..."
+	Hash string `bson:"Hash" json:"hash"`
+	// CreatedAt is when the token was created
+	CreatedAt time.Time `bson:"CreatedAt" json:"createdAt"`
+}
+
+// Allows reports whether the token may make a request with the given HTTP method.
+// Read-only tokens may only make GET and HEAD requests.
+func (t APIToken) Allows(method string) bool {
+	switch t.Scope {
+	case ScopeWrite:
+		return true
+	case ScopeRead:
+		return method == http.MethodGet || method == http.MethodHead
+	default:
+		return false
+	}
+}
+
+// TokenStore keeps the API tokens accepted by the HTTP server.
+type TokenStore interface {
+	// SaveToken stores a new token.
+	SaveToken(ctx context.Context, token APIToken) error
+	// ListTokens returns every token, oldest first.
+	ListTokens(ctx context.Context) ([]APIToken, error)
+	// FindToken returns the token whose secret has the given hash.
+	// Returns an error wrapping ErrNotFound if there is none.
+	FindToken(ctx context.Context, hash string) (APIToken, error)
+	// RevokeToken deletes the token with the given ID, so it is no longer accepted.
+	// Returns an error wrapping ErrNotFound if there is none.
+	RevokeToken(ctx context.Context, id string) error
+}
+
+// hashToken returns the hash a token secret is stored under.
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return checksumPrefix + hex.EncodeToString(sum[:])
+}
+
+// CreateToken creates a token with a random secret and stores its hash.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the write
+//	tokens: The store to save the token in
+//	name: Who or what will use the token, e.g. "mentor"
+//	scope: What the token allows
+//
+// Returns:
+//
+//	The secret to hand to the bearer, e.g. "ugt_Xb3...", which can't be recovered later,
+//	and the stored token; or an error wrapping ErrValidation if the name is empty.
+func CreateToken(ctx context.Context, tokens TokenStore, name string, scope TokenScope) (string, APIToken, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", APIToken{}, fmt.Errorf("%w: the token needs a name", ErrValidation)
+	}
+	if _, err := ParseScope(string(scope)); err != nil {
+		return "", APIToken{}, err
+	}
+
+	secret := make([]byte, 32)
+	id := make([]byte, 4)
+	rand.Read(secret)
+	rand.Read(id)
+
+	plain := tokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	token := APIToken{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Scope:     scope,
+		Hash:      hashToken(plain),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := tokens.SaveToken(ctx, token); err != nil {
+		return "", APIToken{}, err
+	}
+	return plain, token, nil
+}
+
+// VerifyToken returns the stored token matching a secret presented by a client.
+//
+// Parameters:
+//
+//	ctx: Context controlling cancellation and deadline of the lookup
+//	tokens: The store holding the accepted tokens
+//	secret: The secret sent by the client
+//
+// Returns:
+//
+//	The token, or an error wrapping ErrUnauthorized if the secret belongs to no stored
+//	token (e.g. because it was revoked), or another error if the lookup fails.
+func VerifyToken(ctx context.Context, tokens TokenStore, secret string) (APIToken, error) {
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return APIToken{}, fmt.Errorf("%w: not a UniGrades API token", ErrUnauthorized)
+	}
+	token, err := tokens.FindToken(ctx, hashToken(secret))
+	if errors.Is(err, ErrNotFound) {
+		return APIToken{}, fmt.Errorf("%w: unknown or revoked API token", ErrUnauthorized)
+	}
+	return token, err
+}
+
+// MemoryTokens is a TokenStore that keeps tokens in memory, for tests and the file store.
+type MemoryTokens struct {
+	// mu guards tokens against concurrent access
+	mu sync.RWMutex
+	// tokens maps each token ID to the token
+	tokens map[string]APIToken
+}
+
+// NewMemoryTokens creates an empty in-memory TokenStore.
+func NewMemoryTokens() *MemoryTokens {
+	return &MemoryTokens{tokens: make(map[string]APIToken)}
+}
+
+// SaveToken stores a new token. Returns an error wrapping ErrValidation if its ID is taken.
+func (t *MemoryTokens) SaveToken(ctx context.Context, token APIToken) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.tokens[token.ID]; exists {
+		return fmt.Errorf("%w: token ID %s already in use", ErrValidation, token.ID)
+	}
+	t.tokens[token.ID] = token
+	return nil
+}
+
+// ListTokens returns every token, oldest first.
+func (t *MemoryTokens) ListTokens(ctx context.Context) ([]APIToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	list := make([]APIToken, 0, len(t.tokens))
+	for _, token := range t.tokens {
+		list = append(list, token)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list, nil
+}
+
+// FindToken returns the token whose secret has the given hash.
+func (t *MemoryTokens) FindToken(ctx context.Context, hash string) (APIToken, error) {
+	if err := ctx.Err(); err != nil {
+		return APIToken{}, err
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, token := range t.tokens {
+		if token.Hash == hash {
+			return token, nil
+		}
+	}
+	return APIToken{}, fmt.Errorf("token %w", ErrNotFound)
+}
+
+// RevokeToken deletes the token with the given ID.
+func (t *MemoryTokens) RevokeToken(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.tokens[id]; !exists {
+		return fmt.Errorf("token '%s' %w", id, ErrNotFound)
+	}
+	delete(t.tokens, id)
+	return nil
+}
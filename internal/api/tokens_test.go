@@ -0,0 +1,93 @@
+package api
+
+import (
+	// Standard library imports
+	"context"  // Contexts of the store calls
+	"errors"   // Error inspection
+	"net/http" // Request methods
+	"testing"  // Test framework
+)
+
+// TestVerifyToken checks which secrets VerifyToken accepts, and which token it returns for them.
+func TestVerifyToken(t *testing.T) {
+	ctx := context.Background()
+	tokens := NewMemoryTokens()
+	mentor, mentorToken, err := CreateToken(ctx, tokens, "mentor", ScopeRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	laptop, _, err := CreateToken(ctx, tokens, "laptop", ScopeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, revokedToken, err := CreateToken(ctx, tokens, "old phone", ScopeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.RevokeToken(ctx, revokedToken.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		// wantName is the name of the token returned, empty if the secret must be rejected
+		wantName string
+	}{
+		{"read token", mentor, "mentor"},
+		{"write token", laptop, "laptop"},
+		{"revoked token", revoked, ""},
+		{"empty secret", "", ""},
+		{"without prefix", mentor[len(tokenPrefix):], ""},
+		{"unknown secret", tokenPrefix + "unknown", ""},
+		{"changed secret", mentor[:len(mentor)-1] + "x", ""},
+		{"stored hash", mentorToken.Hash, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := VerifyToken(ctx, tokens, tt.secret)
+			if tt.wantName == "" {
+				if !errors.Is(err, ErrUnauthorized) {
+					t.Errorf("VerifyToken() error = %v, want ErrUnauthorized", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyToken() = %v", err)
+			}
+			if token.Name != tt.wantName {
+				t.Errorf("token name = %q, want %q", token.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+// TestTokenAllows checks which request methods each scope allows.
+func TestTokenAllows(t *testing.T) {
+	tests := []struct {
+		scope  TokenScope
+		method string
+		want   bool
+	}{
+		{ScopeRead, http.MethodGet, true},
+		{ScopeRead, http.MethodHead, true},
+		{ScopeRead, http.MethodPost, false},
+		{ScopeRead, http.MethodPatch, false},
+		{ScopeRead, http.MethodPut, false},
+		{ScopeRead, http.MethodDelete, false},
+		{ScopeRead, http.MethodOptions, false},
+		{ScopeWrite, http.MethodGet, true},
+		{ScopeWrite, http.MethodPost, true},
+		{ScopeWrite, http.MethodPatch, true},
+		{ScopeWrite, http.MethodDelete, true},
+		{"admin", http.MethodGet, false},
+		{"", http.MethodGet, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.scope)+" "+tt.method, func(t *testing.T) {
+			if got := (APIToken{Scope: tt.scope}).Allows(tt.method); got != tt.want {
+				t.Errorf("Allows(%s) = %v, want %v", tt.method, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Package api provides database operations for managing course information.
+package api
+
+import (
+	// Standard library imports
+	"context" // Cancellation of the watch
+	"reflect" // Comparison of polled courses
+	"time"    // Poll interval
+)
+
+// ChangeWatcher is implemented by stores that can push a notification whenever the
+// courses of a university change, including changes made by other processes.
+type ChangeWatcher interface {
+	// WatchChanges calls notify after every change to the university's courses until
+	// ctx is done. It returns an error if the store can't watch (e.g. a MongoDB server
+	// without change streams), so the caller can fall back to polling.
+	WatchChanges(ctx context.Context, uni string, notify func()) error
+}
+
+// WatchCourses calls notify whenever the courses of a university change, until ctx is done.
+// A store that implements ChangeWatcher (directly or behind decorators) pushes its changes;
+// if it doesn't, or its watch fails, the courses are polled every interval instead and
+// notify is called whenever they differ from the previous poll. Bursts of changes may
+// result in a single notification, so callers should reload rather than count calls.
+//
+// Parameters:
+//
+//	ctx: Context that stops the watch when canceled
+//	store: The store whose courses are watched
+//	uni: The university whose courses are watched
+//	interval: How often to poll when changes can't be pushed; 0 disables polling
+//	notify: Called after each detected change; must not block
+func WatchCourses(ctx context.Context, store CourseStore, uni string, interval time.Duration, notify func()) {
+	if watcher, ok := FindStore[ChangeWatcher](store); ok {
+		if err := watcher.WatchChanges(ctx, uni, notify); err == nil || ctx.Err() != nil {
+			return
+		}
+	}
+	if interval > 0 {
+		pollCourses(ctx, store, uni, interval, notify)
+	}
+}
+
+// pollCourses loads the courses every interval and calls notify when they changed.
+// Failed loads are skipped; the next successful one is compared with the last known courses.
+func pollCourses(ctx context.Context, store CourseStore, uni string, interval time.Duration, notify func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, err := pollOnce(ctx, store, uni)
+	known := err == nil
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		courses, err := pollOnce(ctx, store, uni)
+		if err != nil {
+			continue
+		}
+		if known && !reflect.DeepEqual(courses, last) {
+			notify()
+		}
+		last, known = courses, true
+	}
+}
+
+// pollOnce loads the valid courses of a university. Warnings such as malformed documents
+// don't count as a failure, so a single bad document doesn't stop changes to the others
+// from being seen.
+func pollOnce(ctx context.Context, store CourseStore, uni string) ([]Course, error) {
+	courses, err := store.GetAllCourses(ctx, uni)
+	if IsWarning(err) {
+		return courses, nil
+	}
+	return courses, err
+}
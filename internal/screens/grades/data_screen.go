@@ -3,14 +3,17 @@ package grades
 
 import (
 	// Standard library imports
+	"context" // Cancellation of in-flight operations
+	"errors"  // Conflict detection
 	"fmt"     // Formatted I/O and string conversion
 	"strconv" // String to number conversions
 	"strings" // String manipulation
+	"time"    // Refresh interval of the live course watch
 
 	// Terminal UI libraries
+	tea "github.com/charmbracelet/bubbletea"  // TUI framework
 	"github.com/charmbracelet/lipgloss"       // Styling and layout
 	"github.com/charmbracelet/lipgloss/table" // Table rendering
-	"go.mongodb.org/mongo-driver/v2/mongo"    // MongoDB client
 
 	// Internal packages
 	"UniGrades/internal/api"        // Database operations
@@ -22,7 +25,7 @@ import (
 // This interface allows the data screen to interact with the picker model
 // without creating circular dependencies between packages.
 type DataScreenModel interface {
-	GetMongoClient() *mongo.Client
+	GetStore() api.CourseStore
 	GetSelectedUniversity() string
 	GetTermWidth() int
 	GetTableStr() string
@@ -33,7 +36,12 @@ type DataScreenModel interface {
 	GetTextInputView() string
 	GetStatusMessage() string
 	SetStatusMessage(msg string)
-	RefreshCourses()
+	GetCourseCount() int
+	GetLoadError() error
+	SetCourses(courses []api.Course, stats api.CourseStats, err error)
+	GetOperation() string
+	SetOperation(label string, cancel context.CancelFunc)
+	CancelOperation()
 	RefreshTableStr(lipgloss.Color)
 	RefreshAvgStr(lipgloss.Color)
 	RefreshAvgPerYearStr(lipgloss.Color)
@@ -41,44 +49,82 @@ type DataScreenModel interface {
 	RefreshEctsStr(lipgloss.Color)
 	GetTextInputValue() string
 	SetTextInputValue(string)
+	GetUndoStack() *UndoStack
+	GetHistoryStore() api.HistoryStore
+	GetPanelStr() string
+	SetPanelStr(string)
+	GetCourses() []api.Course
+	GetColumns() []api.Column
+	GetRefreshInterval() time.Duration
+	SetWatch(cancel context.CancelFunc)
 }
 
 // HandleDataScreenInput processes user text input on the data screen.
 // It parses commands prefixed with '/' and delegates to appropriate handlers.
-func HandleDataScreenInput(m DataScreenModel) {
+// Commands run in the background; the returned command delivers their result.
+// Only one operation runs at a time, so input is rejected while another is in flight.
+func HandleDataScreenInput(m DataScreenModel) tea.Cmd {
 	input := m.GetTextInputValue()
+	if !strings.HasPrefix(input, "/") {
+		return nil
+	}
+	if m.GetOperation() != "" {
+		m.SetStatusMessage("Please wait: " + m.GetOperation() + " (Esc to cancel)")
+		return nil
+	}
+
+	var cmd tea.Cmd
 	if strings.HasPrefix(input, "/add ") {
-		ProcessAddCommand(m, input)
+		cmd = ProcessAddCommand(m, input)
 		m.SetTextInputValue("")
 	} else if strings.HasPrefix(input, "/delete ") {
-		ProcessDeleteCommand(m, input)
+		cmd = ProcessDeleteCommand(m, input)
 		m.SetTextInputValue("")
 	} else if strings.HasPrefix(input, "/edit ") {
-		ProcessEditCommand(m, input)
+		cmd = ProcessEditCommand(m, input)
+		m.SetTextInputValue("")
+	} else if input == "/trash" {
+		cmd = ProcessTrashCommand(m)
+		m.SetTextInputValue("")
+	} else if strings.HasPrefix(input, "/restore ") {
+		cmd = ProcessRestoreCommand(m, input)
+		m.SetTextInputValue("")
+	} else if input == "/undo" {
+		cmd = Undo(m)
+		m.SetTextInputValue("")
+	} else if input == "/redo" {
+		cmd = Redo(m)
+		m.SetTextInputValue("")
+	} else if input == "/history" || strings.HasPrefix(input, "/history ") {
+		cmd = ProcessHistoryCommand(m, input)
+		m.SetTextInputValue("")
+	} else if input == "/sync" {
+		cmd = ProcessSyncCommand(m)
 		m.SetTextInputValue("")
 	}
+	return cmd
 }
 
-// ProcessAddCommand parses and executes the /add command.
+// ProcessAddCommand parses the /add command and starts adding the course.
 // Format: /add Name Year Grade ECTS
 // Example: /add Applied_Math 1 7 5
-func ProcessAddCommand(m DataScreenModel, input string) {
+func ProcessAddCommand(m DataScreenModel, input string) tea.Cmd {
 	// Parse command arguments
 	parts := strings.Fields(input)
 	if len(parts) < 5 {
 		m.SetStatusMessage("Invalid format. Use: /add Name Year Grade ECTS")
-		return
+		return nil
 	}
 
 	name := parts[1]
 	year, errYear := strconv.Atoi(parts[2])
 	grade, errGrade := strconv.ParseFloat(parts[3], 64)
-	ects, errEcts := strconv.Atoi(parts[4])
+	ects, errEcts := strconv.ParseFloat(parts[4], 64)
 
 	// Validate all numeric conversions
 	if errYear != nil || errGrade != nil || errEcts != nil {
-		m.SetStatusMessage("Error: Year and ECTS must be integers, Grade must be a number")
-		return
+		m.SetStatusMessage("Error: Year must be an integer, Grade and ECTS must be numbers")
+		return nil
 	}
 
 	// Create course struct and insert into database
@@ -89,100 +135,320 @@ func ProcessAddCommand(m DataScreenModel, input string) {
 		ECTS:  ects,
 	}
 
-	id, err := api.AddCourse(m.GetMongoClient(), course)
-	if err != nil {
-		m.SetStatusMessage(fmt.Sprintf("Error adding course: %v", err))
-		return
-	}
-
-	// Refresh all displays to show the new course
-	m.RefreshCourses()
-	RefreshCharts(m)
-
-	m.SetStatusMessage(fmt.Sprintf("✓ Course '%s' added successfully (ID: %s)", name, id))
+	store := m.GetStore()
+	uni := m.GetSelectedUniversity()
+	return StartOperation(m, "Adding course", func(ctx context.Context) tea.Msg {
+		id, err := store.AddCourse(ctx, uni, course)
+		if err != nil && !api.IsWarning(err) {
+			return CommandDoneMsg{University: uni, Status: fmt.Sprintf("Error adding course: %v", err), Err: err}
+		}
+
+		// Reload all data to show the new course
+		course.ID, _ = api.ParseID(id)
+		change := AddChange(course)
+		return CommandDoneMsg{
+			University: uni,
+			Status:     withWarning(fmt.Sprintf("✓ Course '%s' added successfully (ID: %s)", name, id), err),
+			Change:     &change,
+			Reload:     loadCourses(ctx, store, uni),
+		}
+	})
 }
 
-// ProcessDeleteCommand parses and executes the /delete command.
-// Format: /delete CourseName
+// ProcessDeleteCommand parses the /delete command and starts moving the course to the trash.
+// The course may be given by name or by ID; ambiguous names are rejected.
+// Format: /delete CourseName|ID
 // Example: /delete Applied_Math
-func ProcessDeleteCommand(m DataScreenModel, input string) {
+func ProcessDeleteCommand(m DataScreenModel, input string) tea.Cmd {
 	// Parse command arguments
 	parts := strings.Fields(input)
 	if len(parts) < 2 {
-		m.SetStatusMessage("Invalid format. Use: /delete CourseName")
-		return
+		m.SetStatusMessage("Invalid format. Use: /delete CourseName|ID")
+		return nil
 	}
 
-	courseName := parts[1]
+	ref := parts[1]
+
+	store := m.GetStore()
+	uni := m.GetSelectedUniversity()
+	shown := m.GetCourses()
+	return StartOperation(m, "Deleting course", func(ctx context.Context) tea.Msg {
+		// Find the single course the user means
+		course, err := api.ResolveCourse(ctx, store, uni, ref)
+		if err != nil {
+			return CommandDoneMsg{University: uni, Status: fmt.Sprintf("Error deleting course: %v", err), Err: err}
+		}
+
+		// Delete course from database, unless it changed since the user last saw it
+		seen := seenCourse(shown, course)
+		err = store.DeleteCourse(ctx, uni, course.ID.Hex(), seen.Revision)
+		if err != nil && !api.IsWarning(err) {
+			return commandFailed(ctx, store, uni, "Error deleting course", seen, err)
+		}
+
+		// Reload all data
+		change := DeleteChange(course)
+		return CommandDoneMsg{
+			University: uni,
+			Status:     withWarning(fmt.Sprintf("✓ Course '%s' (ID: %s) moved to the trash", course.Name, course.ShortID()), err),
+			Change:     &change,
+			Reload:     loadCourses(ctx, store, uni),
+		}
+	})
+}
 
-	// Delete course from database
-	err := api.DeleteCourse(m.GetMongoClient(), courseName)
+// ProcessEditCommand parses the /edit command and starts updating the course.
+// The course may be given by name or by ID; ambiguous names are rejected.
+// Several fields can be changed at once with Field=Value pairs; they are written together,
+// and nothing is written unless every value is valid.
+// Format: /edit CourseName|ID Field NewValue
+// Format: /edit CourseName|ID Field=NewValue [Field=NewValue ...]
+// Valid fields: Name, Year, Grade, ECTS
+// Example: /edit Applied_Math Grade 9
+// Example: /edit Applied_Math Grade=8.5 ECTS=5
+func ProcessEditCommand(m DataScreenModel, input string) tea.Cmd {
+	// Parse command arguments
+	parts := strings.Fields(input)
+	if len(parts) < 3 {
+		m.SetStatusMessage("Invalid format. Use: /edit CourseName|ID Field=Value [Field=Value ...] (e.g., /edit Applied_Math Grade=8.5 ECTS=5)")
+		return nil
+	}
+
+	ref := parts[1]
+	fields, updates, err := parseEditArgs(parts[2:])
 	if err != nil {
-		m.SetStatusMessage(fmt.Sprintf("Error deleting course: %v", err))
-		return
+		m.SetStatusMessage(err.Error())
+		return nil
 	}
 
-	// Refresh all displays
-	m.RefreshCourses()
-	RefreshCharts(m)
+	// Describe the changes in the order they were given
+	changes := make([]string, len(fields))
+	for i, field := range fields {
+		changes[i] = fmt.Sprintf("%s=%s", field, updates[field])
+	}
 
-	m.SetStatusMessage(fmt.Sprintf("✓ Course '%s' deleted successfully", courseName))
+	store := m.GetStore()
+	uni := m.GetSelectedUniversity()
+	shown := m.GetCourses()
+	return StartOperation(m, "Updating course", func(ctx context.Context) tea.Msg {
+		// Find the single course the user means
+		course, err := api.ResolveCourse(ctx, store, uni, ref)
+		if err != nil {
+			return CommandDoneMsg{University: uni, Status: fmt.Sprintf("Error updating course: %v", err), Err: err}
+		}
+
+		// Update all fields of the course in one write, unless it changed since the user last saw it
+		seen := seenCourse(shown, course)
+		err = store.UpdateCourse(ctx, uni, course.ID.Hex(), seen.Revision, updates)
+		if err != nil && !api.IsWarning(err) {
+			return commandFailed(ctx, store, uni, "Error updating course", seen, err)
+		}
+
+		// Reload all data
+		change := UpdateChange(course, updates)
+		return CommandDoneMsg{
+			University: uni,
+			Status:     withWarning(fmt.Sprintf("✓ Course '%s' (ID: %s) updated: %s", course.Name, course.ShortID(), strings.Join(changes, ", ")), err),
+			Change:     &change,
+			Reload:     loadCourses(ctx, store, uni),
+		}
+	})
 }
 
-// ProcessEditCommand parses and executes the /edit command.
-// Format: /edit CourseName Field NewValue
-// Valid fields: Name, Year, Grade, ECTS
-// Example: /edit Applied_Math Grade 9
-func ProcessEditCommand(m DataScreenModel, input string) {
+// seenCourse returns the course as the table showed it when the user typed the command,
+// or the resolved course if it isn't shown (e.g. nothing was loaded yet).
+func seenCourse(shown []api.Course, resolved api.Course) api.Course {
+	for _, c := range shown {
+		if c.ID == resolved.ID {
+			return c
+		}
+	}
+	return resolved
+}
+
+// withWarning appends the warning of a command that succeeded with one (e.g. a change
+// whose history entry couldn't be recorded) to its status.
+func withWarning(status string, warning error) string {
+	if warning == nil {
+		return status
+	}
+	return status + " | Warning: " + warning.Error()
+}
+
+// commandFailed reports a failed /edit or /delete. If the course was changed elsewhere
+// since the user saw it, the status shows the current values on the server and the
+// table is reloaded, so the user can decide whether to retry.
+func commandFailed(ctx context.Context, store api.CourseStore, uni, prefix string, seen api.Course, err error) CommandDoneMsg {
+	var conflict *api.ConflictError
+	if !errors.As(err, &conflict) {
+		return CommandDoneMsg{University: uni, Status: fmt.Sprintf("%s: %v", prefix, err), Err: err}
+	}
+
+	changes := "no visible fields changed"
+	if diff := conflict.Changes(seen); len(diff) > 0 {
+		changes = "now " + strings.Join(diff, ", ")
+	}
+	return CommandDoneMsg{
+		University: uni,
+		Status:     fmt.Sprintf("%s: '%s' was changed elsewhere (%s); reloaded, check and retry", prefix, seen.Name, changes),
+		Err:        err,
+		Reload:     loadCourses(ctx, store, uni),
+	}
+}
+
+// parseEditArgs parses the field arguments of the /edit command.
+// It accepts either a single "Field Value" pair or any number of "Field=Value" pairs.
+//
+// Returns:
+//
+//	The field names in the order given, the updates keyed by field name, or an error
+//	describing the first invalid argument.
+func parseEditArgs(args []string) ([]string, map[string]string, error) {
+	validFields := map[string]bool{"Name": true, "Year": true, "Grade": true, "ECTS": true}
+
+	// Legacy form: /edit CourseName Field NewValue
+	if len(args) == 2 && !strings.Contains(args[0], "=") && !strings.Contains(args[1], "=") {
+		args = []string{args[0] + "=" + args[1]}
+	}
+
+	var fields []string
+	updates := make(map[string]string)
+	for _, arg := range args {
+		field, value, ok := strings.Cut(arg, "=")
+		if !ok || value == "" {
+			return nil, nil, fmt.Errorf("Invalid argument '%s'. Use Field=Value (e.g., Grade=8.5)", arg)
+		}
+		if !validFields[field] {
+			return nil, nil, fmt.Errorf("Invalid field '%s'. Valid fields are: Name, Year, Grade, ECTS", field)
+		}
+		if _, dup := updates[field]; dup {
+			return nil, nil, fmt.Errorf("Field '%s' is given more than once", field)
+		}
+		fields = append(fields, field)
+		updates[field] = value
+	}
+	return fields, updates, nil
+}
+
+// ProcessTrashCommand starts loading the trashed courses of the selected university.
+// Format: /trash
+func ProcessTrashCommand(m DataScreenModel) tea.Cmd {
+	store := m.GetStore()
+	uni := m.GetSelectedUniversity()
+	return StartOperation(m, "Loading trash", func(ctx context.Context) tea.Msg {
+		courses, err := store.GetTrashedCourses(ctx, uni)
+		return TrashLoadedMsg{University: uni, Courses: courses, Err: err}
+	})
+}
+
+// ProcessRestoreCommand parses the /restore command and starts moving the course
+// out of the trash. The course may be given by name or by ID; ambiguous names are rejected.
+// Format: /restore CourseName|ID
+// Example: /restore Applied_Math
+func ProcessRestoreCommand(m DataScreenModel, input string) tea.Cmd {
 	// Parse command arguments
 	parts := strings.Fields(input)
-	if len(parts) < 4 {
-		m.SetStatusMessage("Invalid format. Use: /edit CourseName Field NewValue (e.g., /edit Applied_Math Grade 9)")
-		return
+	if len(parts) != 2 {
+		m.SetStatusMessage("Invalid format. Use: /restore CourseName|ID")
+		return nil
 	}
 
-	courseName := parts[1]
-	field := parts[2]
-	newValue := parts[3]
+	ref := parts[1]
+
+	store := m.GetStore()
+	uni := m.GetSelectedUniversity()
+	return StartOperation(m, "Restoring course", func(ctx context.Context) tea.Msg {
+		// Find the single trashed course the user means
+		course, err := api.ResolveTrashedCourse(ctx, store, uni, ref)
+		if err != nil {
+			return CommandDoneMsg{University: uni, Status: fmt.Sprintf("Error restoring course: %v", err), Err: err}
+		}
+
+		// Move the course back to the active courses
+		err = store.RestoreCourse(ctx, uni, course.ID.Hex())
+		if err != nil && !api.IsWarning(err) {
+			return CommandDoneMsg{University: uni, Status: fmt.Sprintf("Error restoring course: %v", err), Err: err}
+		}
+
+		// Reload all data
+		change := RestoreChange(course)
+		return CommandDoneMsg{
+			University: uni,
+			Status:     withWarning(fmt.Sprintf("✓ Course '%s' (ID: %s) restored", course.Name, course.ShortID()), err),
+			Reload:     loadCourses(ctx, store, uni),
+			Change:     &change,
+		}
+	})
+}
 
-	// Validate field name
-	validFields := map[string]bool{"Name": true, "Year": true, "Grade": true, "ECTS": true}
-	if !validFields[field] {
-		m.SetStatusMessage("Invalid field. Valid fields are: Name, Year, Grade, ECTS")
-		return
+// ProcessHistoryCommand parses the /history command and starts loading the history.
+// Without a course, the history of every course of the university is shown. The course
+// may be given by name or by ID, and deleted courses can be looked up too.
+// Format: /history [CourseName|ID]
+// Example: /history Applied_Math
+func ProcessHistoryCommand(m DataScreenModel, input string) tea.Cmd {
+	// Parse command arguments
+	parts := strings.Fields(input)
+	if len(parts) > 2 {
+		m.SetStatusMessage("Invalid format. Use: /history [CourseName|ID]")
+		return nil
 	}
 
-	// Update course in database
-	err := api.UpdateCourse(m.GetMongoClient(), courseName, field, newValue)
-	if err != nil {
-		m.SetStatusMessage(fmt.Sprintf("Error updating course: %v", err))
-		return
+	ref := ""
+	if len(parts) == 2 {
+		ref = parts[1]
 	}
 
-	// Refresh all displays
-	m.RefreshCourses()
-	RefreshCharts(m)
+	history := m.GetHistoryStore()
+	if history == nil {
+		m.SetStatusMessage("History is not available for this store")
+		return nil
+	}
+	uni := m.GetSelectedUniversity()
+	return StartOperation(m, "Loading history", func(ctx context.Context) tea.Msg {
+		entries, err := history.GetHistory(ctx, uni)
+		return HistoryLoadedMsg{University: uni, Ref: ref, Entries: entries, Err: err}
+	})
+}
+
+// ClosePanel hides the history or trash panel, returning to the table and charts.
+// Returns true if a panel was shown.
+func ClosePanel(m DataScreenModel) bool {
+	if m.GetPanelStr() == "" {
+		return false
+	}
+	m.SetPanelStr("")
+	m.SetStatusMessage("")
+	return true
+}
 
-	m.SetStatusMessage(fmt.Sprintf("✓ Course '%s' field '%s' updated to '%v'", courseName, field, newValue))
+// RetryLoad starts reloading the courses, e.g. after a failed load.
+func RetryLoad(m DataScreenModel) tea.Cmd {
+	if m.GetOperation() != "" {
+		return nil
+	}
+	m.SetStatusMessage("")
+	return LoadCourses(m)
 }
 
 // RefreshCharts updates all chart and statistics displays.
 // Recomputes tables and visualizations based on current university and course data.
 func RefreshCharts(m DataScreenModel) {
-	selectedUni := m.GetSelectedUniversity()
-	color := tui.DefaultColor
-	if selectedUni != "" {
-		color = university.ColorMap()[selectedUni]
-	}
+	color := universityColor(m.GetSelectedUniversity())
 
-	// Only refresh charts for universities with data
-	if selectedUni != "TUD" && selectedUni != "TUM" {
-		m.RefreshTableStr(color)
-		m.RefreshAvgStr(color)
-		m.RefreshAvgPerYearStr(color)
-		m.RefreshAvgECTSPerYearStr(color)
-		m.RefreshEctsStr(color)
+	m.RefreshTableStr(color)
+	m.RefreshAvgStr(color)
+	m.RefreshAvgPerYearStr(color)
+	m.RefreshAvgECTSPerYearStr(color)
+	m.RefreshEctsStr(color)
+}
+
+// universityColor returns the brand color of a university, or the default color if none is selected.
+func universityColor(uni string) lipgloss.Color {
+	if uni == "" {
+		return tui.DefaultColor
 	}
+	return university.ColorMap()[uni]
 }
 
 // RenderDataScreen renders the complete data/grades screen display.
@@ -190,57 +456,42 @@ func RefreshCharts(m DataScreenModel) {
 func RenderDataScreen(m DataScreenModel) string {
 	// Get selected university and its color
 	selectedUni := m.GetSelectedUniversity()
-	uniColor := tui.DefaultColor
-	if selectedUni != "" {
-		uniColor = university.ColorMap()[selectedUni]
-	}
-
-	// Check if data is unavailable for this university (e.g., future studies)
-	if selectedUni == "TUD" || selectedUni == "TUM" {
-		message := fmt.Sprintf("Data unavailable: Studies at %s have not started yet.", selectedUni)
-		msgBox := lipgloss.NewStyle().
-			Border(lipgloss.NormalBorder()).
-			BorderForeground(uniColor).
-			Padding(1, 2).
-			Foreground(ColorDimText).
-			Render(message)
-
-		contentStyle := lipgloss.NewStyle().
-			Width(m.GetTermWidth()).
-			Align(lipgloss.Center)
-
-		s := "\n" + contentStyle.Render(msgBox) + "\n"
-
-		footerStyle := lipgloss.NewStyle().
-			Width(m.GetTermWidth()).
-			Align(lipgloss.Center)
-		s += "\n" + footerStyle.Render("Press Ctrl + Q to go back, Ctrl + C to quit.") + "\n"
-
-		return s
-	}
+	uniColor := universityColor(selectedUni)
 
 	gap := "   "
 
-	// Get all rendered visualization strings from model
-	tableStr := m.GetTableStr()
-	avgStr := m.GetAvgStr()
-	avgPerYearStr := m.GetAvgPerYearStr()
-	avgECTSPerYearStr := m.GetAvgECTSPerYearStr()
-	ectsStr := m.GetEctsStr()
-
-	// Organize columns: average stats + per-year average chart
-	col2 := lipgloss.JoinVertical(lipgloss.Left, avgStr, avgPerYearStr, "")
-
-	// Third column: per-year ECTS chart + total ECTS bar
-	col3 := lipgloss.JoinVertical(lipgloss.Left, avgECTSPerYearStr, "", ectsStr)
-
 	// Fourth column: help sections with command reference and error explanations
 	helpCommands := RenderCommandsHelp(uniColor)
 	helpErrors := RenderErrorsExplanation(uniColor)
 	helpSection := lipgloss.JoinVertical(lipgloss.Left, helpCommands, "", helpErrors)
 
-	// Main layout: arrange all columns horizontally
-	grid := lipgloss.JoinHorizontal(lipgloss.Top, tableStr, gap, col2, gap, col3, gap, helpSection)
+	var grid string
+	if panelStr := m.GetPanelStr(); panelStr != "" {
+		// History or trash panel: replaces the table and charts until closed
+		grid = lipgloss.JoinHorizontal(lipgloss.Top, panelStr, gap, helpSection)
+	} else if err := m.GetLoadError(); err != nil {
+		// Error state: the courses could not be loaded, offer a retry
+		grid = lipgloss.JoinHorizontal(lipgloss.Top, RenderErrorState(selectedUni, err, uniColor), gap, helpSection)
+	} else if m.GetCourseCount() == 0 {
+		// Empty state: no courses recorded yet, show a hint next to the help section
+		grid = lipgloss.JoinHorizontal(lipgloss.Top, RenderEmptyState(selectedUni, uniColor), gap, helpSection)
+	} else {
+		// Get all rendered visualization strings from model
+		tableStr := m.GetTableStr()
+		avgStr := m.GetAvgStr()
+		avgPerYearStr := m.GetAvgPerYearStr()
+		avgECTSPerYearStr := m.GetAvgECTSPerYearStr()
+		ectsStr := m.GetEctsStr()
+
+		// Organize columns: average stats + per-year average chart
+		col2 := lipgloss.JoinVertical(lipgloss.Left, avgStr, avgPerYearStr, "")
+
+		// Third column: per-year ECTS chart + total ECTS bar
+		col3 := lipgloss.JoinVertical(lipgloss.Left, avgECTSPerYearStr, "", ectsStr)
+
+		// Main layout: arrange all columns horizontally
+		grid = lipgloss.JoinHorizontal(lipgloss.Top, tableStr, gap, col2, gap, col3, gap, helpSection)
+	}
 
 	// Create text input box with appropriate styling
 	gridWidth := lipgloss.Width(grid)
@@ -260,8 +511,11 @@ func RenderDataScreen(m DataScreenModel) string {
 
 	s := "\n" + centeredInput + "\n"
 
-	// Display status message if available
+	// Display status message if available; a running operation takes precedence
 	statusMsg := m.GetStatusMessage()
+	if op := m.GetOperation(); op != "" {
+		statusMsg = op + "..."
+	}
 	if statusMsg != "" {
 		statusStyle := lipgloss.NewStyle().
 			Foreground(ColorSuccess).
@@ -285,11 +539,36 @@ func RenderDataScreen(m DataScreenModel) string {
 	footerStyle := lipgloss.NewStyle().
 		Width(m.GetTermWidth()).
 		Align(lipgloss.Center)
-	s += "\n" + footerStyle.Render("Press Ctrl + Q to go back, Ctrl + C to quit.") + "\n"
+	s += "\n" + footerStyle.Render("Press Ctrl + Q to go back, Ctrl + R to reload, Ctrl + Z/Y to undo/redo, Esc to cancel or close, Ctrl + C to quit.") + "\n"
 
 	return s
 }
 
+// RenderEmptyState renders the message shown in place of the table and charts
+// when the selected university has no courses yet.
+func RenderEmptyState(selectedUni string, uniColor lipgloss.Color) string {
+	message := fmt.Sprintf("No courses recorded for %s yet.\n\nAdd your first course with:\n/add Name Year Grade ECTS", selectedUni)
+	return lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(uniColor).
+		Padding(1, 2).
+		Foreground(ColorDimText).
+		Render(message)
+}
+
+// RenderErrorState renders the message shown in place of the table and charts
+// when the courses of the selected university could not be loaded.
+func RenderErrorState(selectedUni string, err error, uniColor lipgloss.Color) string {
+	message := fmt.Sprintf("Could not load courses for %s:\n%v\n\nPress Ctrl + R to retry.", selectedUni, err)
+	return lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(uniColor).
+		Padding(1, 2).
+		Foreground(ColorError).
+		Width(EmptyStateWidth).
+		Render(message)
+}
+
 // RenderCommandsHelp renders a table showing available commands and their usage.
 func RenderCommandsHelp(uniColor lipgloss.Color) string {
 	t := table.New().
@@ -308,8 +587,14 @@ func RenderCommandsHelp(uniColor lipgloss.Color) string {
 		Headers("Command", "Description", "Example").
 		Rows(
 			[]string{"/add", "Add new course", "/add Applied_Math 1 7 5"},
-			[]string{"/edit", "Update course field", "/edit Applied_Math Grade 9"},
-			[]string{"/delete", "Delete course", "/delete Applied_math"},
+			[]string{"/edit", "Update course fields", "/edit Applied_Math Grade=8.5 ECTS=5"},
+			[]string{"/delete", "Move course to the trash", "/delete 1a2b3c4d"},
+			[]string{"/trash", "List trashed courses", "/trash"},
+			[]string{"/restore", "Restore trashed course", "/restore Applied_Math"},
+			[]string{"/undo", "Undo last change (Ctrl + Z)", "/undo"},
+			[]string{"/redo", "Redo undone change (Ctrl + Y)", "/redo"},
+			[]string{"/history", "Show changes (all or one course)", "/history Applied_Math"},
+			[]string{"/sync", "Sync offline changes, show conflicts", "/sync"},
 		)
 
 	return t.Render()
@@ -333,10 +618,12 @@ func RenderErrorsExplanation(uniColor lipgloss.Color) string {
 		Headers("Error", "Explanation").
 		Rows(
 			[]string{"Invalid format", "Wrong command syntax"},
-			[]string{"Course not found", "Course name doesn't exist"},
+			[]string{"Course not found", "Course name or ID doesn't exist"},
+			[]string{"Ambiguous", "Several courses match, use the ID"},
+			[]string{"Storage unavailable", "Database unreachable, retry"},
 			[]string{"Year not integer", "Year must be a number"},
 			[]string{"Grade not number", "Grade must be decimal/int"},
-			[]string{"ECTS not integer", "ECTS must be a number"},
+			[]string{"ECTS not number", "ECTS must be decimal/int"},
 			[]string{"Invalid field", "Field not in Name/Year/Grade/ECTS"},
 		)
 
@@ -3,9 +3,12 @@ package grades
 
 import "github.com/charmbracelet/lipgloss"
 
+// EmptyStateWidth is the width of the empty-state and error-state message boxes
+const EmptyStateWidth = 50
+
 // Color constants for data screen styling.
 const (
-	// ColorDimText is a dim grey color used for unavailable message text
+	// ColorDimText is a dim grey color used for empty-state message text
 	ColorDimText = lipgloss.Color("243")
 	// ColorTableAlternate1 is light grey for alternating table rows (even rows)
 	ColorTableAlternate1 = lipgloss.Color("245")
@@ -13,4 +16,6 @@ const (
 	ColorTableAlternate2 = lipgloss.Color("241")
 	// ColorSuccess is green used for success messages
 	ColorSuccess = lipgloss.Color("42")
+	// ColorError is red used for error states
+	ColorError = lipgloss.Color("196")
 )
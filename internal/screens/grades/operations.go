@@ -0,0 +1,262 @@
+// Package grades provides the data/grades screen for displaying course information and statistics.
+package grades
+
+import (
+	// Standard library imports
+	"context" // Cancellation of in-flight operations
+	"errors"  // Error inspection
+	"fmt"     // Formatted I/O
+	"strings" // String manipulation
+
+	// Bubble Tea framework
+	tea "github.com/charmbracelet/bubbletea"
+
+	// Internal packages
+	"UniGrades/internal/api" // Database operations
+	"UniGrades/internal/tui" // UI rendering
+)
+
+// CoursesLoadedMsg is sent when the courses of a university have been loaded from the store.
+type CoursesLoadedMsg struct {
+	// University is the university the courses were loaded for
+	University string
+	// Courses are the loaded courses
+	Courses []api.Course
+	// Stats are the statistics of the courses, computed by the store
+	Stats api.CourseStats
+	// Warning reports skipped malformed documents or courses served from the offline cache
+	Warning string
+	// Err is the error that stopped the load, if any
+	Err error
+}
+
+// CommandDoneMsg is sent when a data screen command has finished running against the store.
+type CommandDoneMsg struct {
+	// University is the university the command ran against
+	University string
+	// Status is the message shown to the user once the command finished
+	Status string
+	// Err is the error that stopped the command, if any
+	Err error
+	// Reload holds the courses reloaded after a successful command, or after a command
+	// that failed because the course was changed elsewhere
+	Reload CoursesLoadedMsg
+	// Change is the change the command made, recorded on the undo stack on success
+	Change *Change
+	// Direction tells whether Change is a new command, an undo or a redo
+	Direction UndoDirection
+}
+
+// HistoryLoadedMsg is sent when the history of a university has been loaded from the store.
+type HistoryLoadedMsg struct {
+	// University is the university the history was loaded for
+	University string
+	// Ref is the course name or ID the history was requested for, empty for all courses
+	Ref string
+	// Entries are the loaded history entries, newest first
+	Entries []api.HistoryEntry
+	// Err is the error that stopped the load, if any
+	Err error
+}
+
+// TrashLoadedMsg is sent when the trashed courses of a university have been loaded from the store.
+type TrashLoadedMsg struct {
+	// University is the university the trash was loaded for
+	University string
+	// Courses are the trashed courses
+	Courses []api.Course
+	// Err is the error that stopped the load, if any
+	Err error
+}
+
+// StartOperation runs op in the background as a Bubble Tea command.
+// The operation receives a context that is canceled when the user presses Esc
+// (see CancelOperation); the label is shown in the status line while it runs.
+//
+// Parameters:
+//
+//	m: The data screen model that tracks the in-flight operation
+//	label: A short description shown while the operation runs (e.g. "Adding course")
+//	op: The work to perform; its result message is delivered to Update
+//
+// Returns:
+//
+//	A tea.Cmd running the operation.
+func StartOperation(m DataScreenModel, label string, op func(ctx context.Context) tea.Msg) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.SetOperation(label, cancel)
+	return func() tea.Msg {
+		defer cancel()
+		return op(ctx)
+	}
+}
+
+// CancelOperation cancels the in-flight operation, if any.
+// The operation's result message still arrives and reports the cancellation.
+// Returns true if an operation was running.
+func CancelOperation(m DataScreenModel) bool {
+	if m.GetOperation() == "" {
+		return false
+	}
+	m.CancelOperation()
+	m.SetOperation("Canceling", nil)
+	return true
+}
+
+// LoadCourses starts loading the courses of the selected university.
+// The result is delivered as a CoursesLoadedMsg.
+func LoadCourses(m DataScreenModel) tea.Cmd {
+	store := m.GetStore()
+	uni := m.GetSelectedUniversity()
+	return StartOperation(m, "Loading courses", func(ctx context.Context) tea.Msg {
+		return loadCourses(ctx, store, uni)
+	})
+}
+
+// loadCourses fetches the courses of a university and their statistics from the store.
+// The statistics come from GetStats, so the charts show what the database computes
+// (e.g. with MongoDB's aggregation pipeline) rather than a second calculation of their own.
+func loadCourses(ctx context.Context, store api.CourseStore, uni string) CoursesLoadedMsg {
+	msg := CoursesLoadedMsg{University: uni}
+	msg.Courses, msg.Err = store.GetAllCourses(ctx, uni)
+	if msg.Err == nil || api.IsWarning(msg.Err) {
+		// Malformed documents are skipped by both, so a warning about them is only shown once
+		stats, err := store.GetStats(ctx, uni)
+		if err != nil && !api.IsWarning(err) {
+			msg.Err = err
+			return msg
+		}
+		msg.Stats = stats
+	}
+
+	// Warnings don't stop the load: show the valid courses and warn about malformed
+	// documents or courses served from the offline cache
+	if api.IsWarning(msg.Err) {
+		msg.Warning = "Warning: " + msg.Err.Error()
+		msg.Err = nil
+	}
+	return msg
+}
+
+// HandleCoursesLoaded applies loaded courses to the model and re-renders all charts.
+// Results for a university other than the selected one are stale and ignored.
+//
+// Returns:
+//
+//	True if the courses were loaded successfully.
+func HandleCoursesLoaded(m DataScreenModel, msg CoursesLoadedMsg) bool {
+	if msg.University != m.GetSelectedUniversity() {
+		return false
+	}
+	m.SetOperation("", nil)
+	if errors.Is(msg.Err, context.Canceled) {
+		// Keep the previously loaded data when the user canceled the load
+		m.SetStatusMessage("Operation canceled")
+		return false
+	}
+	m.SetCourses(msg.Courses, msg.Stats, msg.Err)
+	if msg.Err != nil {
+		return false
+	}
+	RefreshCharts(m)
+	m.SetStatusMessage(msg.Warning)
+	return true
+}
+
+// HandleHistoryLoaded shows the loaded history in place of the table and charts.
+// Results for a university other than the selected one are stale and ignored.
+func HandleHistoryLoaded(m DataScreenModel, msg HistoryLoadedMsg) {
+	if msg.University != m.GetSelectedUniversity() {
+		return
+	}
+	m.SetOperation("", nil)
+	if errors.Is(msg.Err, context.Canceled) {
+		m.SetStatusMessage("Operation canceled")
+		return
+	}
+	if msg.Err != nil {
+		m.SetStatusMessage(fmt.Sprintf("Error loading history: %v", msg.Err))
+		return
+	}
+
+	entries := api.FilterHistory(msg.Entries, msg.Ref)
+	title := "History of all courses"
+	if msg.Ref != "" {
+		title = fmt.Sprintf("History of '%s'", msg.Ref)
+	}
+	if len(entries) == 0 {
+		m.SetStatusMessage("No history recorded for " + strings.TrimPrefix(title, "History of "))
+		return
+	}
+	m.SetPanelStr(tui.RenderHistory(universityColor(m.GetSelectedUniversity()), title, entries))
+	m.SetStatusMessage("Press Esc to close the history")
+}
+
+// HandleTrashLoaded shows the trashed courses in place of the table and charts.
+// Results for a university other than the selected one are stale and ignored.
+func HandleTrashLoaded(m DataScreenModel, msg TrashLoadedMsg) {
+	if msg.University != m.GetSelectedUniversity() {
+		return
+	}
+	m.SetOperation("", nil)
+
+	// Malformed trashed documents can't be restored, so only the valid ones are listed
+	var malformed *api.MalformedError
+	if errors.As(msg.Err, &malformed) {
+		msg.Err = nil
+	}
+	if errors.Is(msg.Err, context.Canceled) {
+		m.SetStatusMessage("Operation canceled")
+		return
+	}
+	if msg.Err != nil {
+		m.SetStatusMessage(fmt.Sprintf("Error loading trash: %v", msg.Err))
+		return
+	}
+	if len(msg.Courses) == 0 {
+		m.SetStatusMessage("The trash is empty")
+		return
+	}
+
+	title := fmt.Sprintf("Trash of %s (%d)", msg.University, len(msg.Courses))
+	m.SetPanelStr(tui.RenderTrash(universityColor(msg.University), title, m.GetColumns(), msg.Courses))
+	m.SetStatusMessage("Use /restore Name|ID to restore a course, Esc to close the trash")
+}
+
+// HandleCommandDone reports the result of a finished command and, on success,
+// records its change for undo and applies the reloaded courses so all charts show the new data.
+// A command that failed with a conflict applies its reload too, so the table shows the
+// values that caused it. Results for a university other than the selected one are stale
+// and ignored, so they neither change the table nor reach the new university's undo stack.
+func HandleCommandDone(m DataScreenModel, msg CommandDoneMsg) {
+	if msg.University != m.GetSelectedUniversity() {
+		return
+	}
+	m.SetOperation("", nil)
+	m.SetPanelStr("")
+	if msg.Err != nil && !errors.Is(msg.Err, api.ErrConflict) {
+		if errors.Is(msg.Err, context.Canceled) {
+			m.SetStatusMessage("Operation canceled")
+		} else {
+			m.SetStatusMessage(msg.Status)
+		}
+		return
+	}
+	if msg.Change != nil && msg.Err == nil {
+		m.GetUndoStack().record(*msg.Change, msg.Direction)
+	}
+	if msg.Reload.University == "" {
+		// Nothing was reloaded, so keep showing the courses already loaded
+		m.SetStatusMessage(msg.Status)
+		return
+	}
+	m.SetCourses(msg.Reload.Courses, msg.Reload.Stats, msg.Reload.Err)
+	if msg.Reload.Err == nil {
+		RefreshCharts(m)
+	}
+	if msg.Reload.Warning != "" {
+		m.SetStatusMessage(msg.Status + " | " + msg.Reload.Warning)
+		return
+	}
+	m.SetStatusMessage(msg.Status)
+}
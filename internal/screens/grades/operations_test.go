@@ -0,0 +1,110 @@
+package grades_test
+
+import (
+	// Standard library imports
+	"context" // Contexts of the store calls
+	"strings" // Inspection of rendered panels
+	"testing" // Test framework
+
+	// Internal packages
+	"UniGrades/internal/api"            // Database operations
+	"UniGrades/internal/screens/grades" // Data screen under test
+	"UniGrades/internal/screens/picker" // Model implementing grades.DataScreenModel
+)
+
+// openScreen returns a model showing the courses of uni, stored in store.
+func openScreen(t *testing.T, store api.CourseStore, uni string) *picker.Model {
+	t.Helper()
+	m := picker.InitialModel(store, nil)
+	for i, name := range m.Choices {
+		if name == uni {
+			m.Selected[i] = struct{}{}
+		}
+	}
+	msg := grades.LoadCourses(&m)()
+	if !grades.HandleCoursesLoaded(&m, msg.(grades.CoursesLoadedMsg)) {
+		t.Fatalf("loading %s failed: %v", uni, m.GetLoadError())
+	}
+	return &m
+}
+
+// TestHandleCommandDoneIgnoresStale checks that a command finishing after another
+// university was opened neither changes its table nor its undo stack, and leaves
+// the operation running there alone.
+func TestHandleCommandDoneIgnoresStale(t *testing.T) {
+	ctx := context.Background()
+	store := api.NewMemoryStore()
+	if _, err := store.AddCourse(ctx, "TUD", api.Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5}); err != nil {
+		t.Fatal(err)
+	}
+	m := openScreen(t, store, "TUD")
+	m.SetOperation("Loading trash", func() {})
+
+	change := grades.AddChange(api.Course{Name: "Applied_Math"})
+	grades.HandleCommandDone(m, grades.CommandDoneMsg{
+		University: "TU/e",
+		Status:     "✓ Course 'Applied_Math' added successfully",
+		Change:     &change,
+	})
+
+	if got := len(m.GetCourses()); got != 1 {
+		t.Errorf("courses = %d, want the 1 course of TUD", got)
+	}
+	if _, ok := m.GetUndoStack().PeekUndo(); ok {
+		t.Error("change of TU/e was recorded on the undo stack of TUD")
+	}
+	if got := m.GetOperation(); got != "Loading trash" {
+		t.Errorf("operation = %q, want the running %q", got, "Loading trash")
+	}
+	if got := m.GetStatusMessage(); got != "" {
+		t.Errorf("status = %q, want none", got)
+	}
+}
+
+// statsStore is a MemoryStore whose statistics differ from those of its courses, as if the
+// database computed them, so tests can tell which of the two the screen shows.
+type statsStore struct {
+	*api.MemoryStore
+	// stats are returned by GetStats
+	stats api.CourseStats
+}
+
+func (s statsStore) GetStats(ctx context.Context, uni string) (api.CourseStats, error) {
+	return s.stats, nil
+}
+
+// TestChartsRenderStoreStats checks that the statistics panels show what the store's
+// GetStats returns instead of recomputing them from the loaded courses.
+func TestChartsRenderStoreStats(t *testing.T) {
+	ctx := context.Background()
+	store := statsStore{MemoryStore: api.NewMemoryStore(), stats: api.CourseStats{
+		University:      "TUD",
+		Count:           1,
+		AverageGrade:    8.25,
+		WeightedAverage: 8.75,
+		TotalECTS:       15,
+		Years:           []api.YearStats{{Year: 1, Count: 1, AverageGrade: 8.25, WeightedAverage: 8.75, TotalECTS: 15}},
+	}}
+	if _, err := store.AddCourse(ctx, "TUD", api.Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5}); err != nil {
+		t.Fatal(err)
+	}
+	m := openScreen(t, store, "TUD")
+
+	tests := []struct {
+		name  string
+		panel string
+		// want is the figure from GetStats the panel must show
+		want string
+	}{
+		{"average grades", m.GetAvgStr(), "8.25"},
+		{"weighted average grades", m.GetAvgStr(), "8.75"},
+		{"average grades per year", m.GetAvgPerYearStr(), "Year 1: 8.25"},
+		{"total ECTS per year", m.GetAvgECTSPerYearStr(), "Year 1: 15"},
+		{"total ECTS", m.GetEctsStr(), "15"},
+	}
+	for _, tt := range tests {
+		if !strings.Contains(tt.panel, tt.want) {
+			t.Errorf("%s panel doesn't show %q from GetStats:\n%s", tt.name, tt.want, tt.panel)
+		}
+	}
+}
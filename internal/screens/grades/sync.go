@@ -0,0 +1,83 @@
+// Package grades provides the data/grades screen for displaying course information and statistics.
+package grades
+
+import (
+	// Standard library imports
+	"context" // Cancellation of in-flight operations
+	"errors"  // Error inspection
+	"fmt"     // Formatted I/O
+
+	// Bubble Tea framework
+	tea "github.com/charmbracelet/bubbletea"
+
+	// Internal packages
+	"UniGrades/internal/api" // Database operations
+	"UniGrades/internal/tui" // UI rendering
+)
+
+// SyncDoneMsg is sent when /sync has tried to replay the changes made offline.
+type SyncDoneMsg struct {
+	// University is the university the sync was started for
+	University string
+	// Status lists the changes still waiting and the conflicts found, taken after the sync
+	Status api.SyncStatus
+	// Err is the error that stopped the sync, if any
+	Err error
+	// Reload holds the courses reloaded after the sync
+	Reload CoursesLoadedMsg
+}
+
+// ProcessSyncCommand starts replaying the changes made offline and then shows what is
+// still waiting and which changes conflicted with the server. Conflicts are only shown
+// once: after this they count as reviewed.
+// Format: /sync
+func ProcessSyncCommand(m DataScreenModel) tea.Cmd {
+	offline, ok := api.FindStore[*api.OfflineStore](m.GetStore())
+	if !ok {
+		m.SetStatusMessage("Nothing to sync: changes are saved directly by this store")
+		return nil
+	}
+
+	store := m.GetStore()
+	uni := m.GetSelectedUniversity()
+	return StartOperation(m, "Syncing changes", func(ctx context.Context) tea.Msg {
+		err := offline.Sync(ctx)
+		status := offline.Status()
+		if len(status.Conflicts) > 0 {
+			offline.ClearConflicts()
+		}
+		return SyncDoneMsg{University: uni, Status: status, Err: err, Reload: loadCourses(ctx, store, uni)}
+	})
+}
+
+// HandleSyncDone shows the result of /sync: the changes still waiting and the conflicts
+// in place of the charts, or a confirmation if everything is synced.
+func HandleSyncDone(m DataScreenModel, msg SyncDoneMsg) {
+	if msg.University != m.GetSelectedUniversity() {
+		return
+	}
+	m.SetOperation("", nil)
+	if errors.Is(msg.Err, context.Canceled) {
+		m.SetStatusMessage("Operation canceled")
+		return
+	}
+
+	if msg.Reload.Err == nil {
+		m.SetCourses(msg.Reload.Courses, msg.Reload.Stats, nil)
+		RefreshCharts(m)
+	}
+
+	status := msg.Status
+	if len(status.Pending) == 0 && len(status.Conflicts) == 0 {
+		m.SetPanelStr("")
+		m.SetStatusMessage("✓ All changes are synced")
+		return
+	}
+
+	m.SetPanelStr(tui.RenderSyncStatus(universityColor(msg.University), status))
+	if msg.Err != nil {
+		m.SetStatusMessage(fmt.Sprintf("Still offline: %v (Esc to close)", msg.Err))
+		return
+	}
+	m.SetStatusMessage("Conflicting changes were not applied and are now dismissed (Esc to close)")
+}
@@ -0,0 +1,220 @@
+// Package grades provides the data/grades screen for displaying course information and statistics.
+package grades
+
+import (
+	// Standard library imports
+	"context" // Cancellation of in-flight operations
+	"errors"  // Conflict detection
+	"fmt"     // Formatted I/O
+
+	// Bubble Tea framework
+	tea "github.com/charmbracelet/bubbletea"
+
+	// Internal packages
+	"UniGrades/internal/api" // Database operations
+)
+
+// UndoLimit is the maximum number of changes that can be undone.
+const UndoLimit = 50
+
+// Mutation is a single store mutation that the undo stack can apply.
+type Mutation struct {
+	// Op is the kind of mutation
+	Op api.HistoryOperation
+	// Course is the course to add (including its ID) or, for other mutations, the target
+	Course api.Course
+	// Updates are the new field values of an update, as accepted by UpdateCourse
+	Updates map[string]string
+}
+
+// Apply performs the mutation on the store.
+// Undo and redo restore a state the user asked for explicitly, so they apply
+// whatever revision the course has by now.
+func (mu Mutation) Apply(ctx context.Context, store api.CourseStore, uni string) error {
+	switch mu.Op {
+	case api.OpAdd:
+		_, err := store.AddCourse(ctx, uni, mu.Course)
+		return err
+	case api.OpUpdate:
+		return store.UpdateCourse(ctx, uni, mu.Course.ID.Hex(), api.AnyRevision, mu.Updates)
+	case api.OpDelete:
+		return store.DeleteCourse(ctx, uni, mu.Course.ID.Hex(), api.AnyRevision)
+	case api.OpRestore:
+		return store.RestoreCourse(ctx, uni, mu.Course.ID.Hex())
+	default:
+		return fmt.Errorf("unknown mutation %q", mu.Op)
+	}
+}
+
+// Change is a command that has been applied to the store, together with its inverse.
+type Change struct {
+	// Description names the change in status messages (e.g. "delete of 'Applied_Math'")
+	Description string
+	// Do re-applies the change
+	Do Mutation
+	// Undo reverts the change
+	Undo Mutation
+}
+
+// AddChange describes adding a course; it is undone by moving the course to the trash,
+// and redone by restoring it from there. The course must carry the ID it was stored under.
+func AddChange(course api.Course) Change {
+	return Change{
+		Description: fmt.Sprintf("add of '%s'", course.Name),
+		Do:          Mutation{Op: api.OpRestore, Course: course},
+		Undo:        Mutation{Op: api.OpDelete, Course: course},
+	}
+}
+
+// DeleteChange describes moving a course to the trash; it is undone by restoring the course.
+func DeleteChange(course api.Course) Change {
+	return Change{
+		Description: fmt.Sprintf("delete of '%s'", course.Name),
+		Do:          Mutation{Op: api.OpDelete, Course: course},
+		Undo:        Mutation{Op: api.OpRestore, Course: course},
+	}
+}
+
+// RestoreChange describes restoring a course from the trash; it is undone by trashing it again.
+func RestoreChange(course api.Course) Change {
+	return Change{
+		Description: fmt.Sprintf("restore of '%s'", course.Name),
+		Do:          Mutation{Op: api.OpRestore, Course: course},
+		Undo:        Mutation{Op: api.OpDelete, Course: course},
+	}
+}
+
+// UpdateChange describes updating fields of a course; it is undone by setting
+// the same fields back to the values they had before.
+func UpdateChange(before api.Course, updates map[string]string) Change {
+	previous := make(map[string]string, len(updates))
+	for field := range updates {
+		value, _ := before.Value(field)
+		previous[field] = fmt.Sprint(value)
+	}
+	return Change{
+		Description: fmt.Sprintf("edit of '%s'", before.Name),
+		Do:          Mutation{Op: api.OpUpdate, Course: before, Updates: updates},
+		Undo:        Mutation{Op: api.OpUpdate, Course: before, Updates: previous},
+	}
+}
+
+// UndoDirection tells how a command's change relates to the undo stack.
+type UndoDirection int
+
+const (
+	// Applied is a new command; its change is pushed onto the undo stack
+	Applied UndoDirection = iota
+	// Undone reverted the most recent change, which moves to the redo stack
+	Undone
+	// Redone re-applied the most recently undone change, which moves back to the undo stack
+	Redone
+)
+
+// UndoStack holds the changes made on the data screen that can be undone and redone.
+// It belongs to one university and is cleared when another university is opened.
+type UndoStack struct {
+	undo []Change
+	redo []Change
+}
+
+// Push records a new change. Any undone changes can no longer be redone.
+func (s *UndoStack) Push(c Change) {
+	s.undo = append(s.undo, c)
+	if len(s.undo) > UndoLimit {
+		s.undo = s.undo[len(s.undo)-UndoLimit:]
+	}
+	s.redo = nil
+}
+
+// PeekUndo returns the change an undo would revert.
+func (s *UndoStack) PeekUndo() (Change, bool) {
+	if len(s.undo) == 0 {
+		return Change{}, false
+	}
+	return s.undo[len(s.undo)-1], true
+}
+
+// PeekRedo returns the change a redo would re-apply.
+func (s *UndoStack) PeekRedo() (Change, bool) {
+	if len(s.redo) == 0 {
+		return Change{}, false
+	}
+	return s.redo[len(s.redo)-1], true
+}
+
+// Clear forgets every change.
+func (s *UndoStack) Clear() {
+	s.undo = nil
+	s.redo = nil
+}
+
+// record moves the change of a finished command onto the right stack.
+func (s *UndoStack) record(c Change, dir UndoDirection) {
+	switch dir {
+	case Applied:
+		s.Push(c)
+	case Undone:
+		s.undo = s.undo[:len(s.undo)-1]
+		s.redo = append(s.redo, c)
+	case Redone:
+		s.redo = s.redo[:len(s.redo)-1]
+		s.undo = append(s.undo, c)
+	}
+}
+
+// Undo starts reverting the most recent change. Once it finishes, all charts
+// are refreshed with the reloaded courses, like after any other command.
+func Undo(m DataScreenModel) tea.Cmd {
+	change, ok := m.GetUndoStack().PeekUndo()
+	if !ok {
+		m.SetStatusMessage("Nothing to undo")
+		return nil
+	}
+	return startChange(m, "Undoing "+change.Description, "✓ Undid "+change.Description, change, change.Undo, Undone)
+}
+
+// Redo starts re-applying the most recently undone change.
+func Redo(m DataScreenModel) tea.Cmd {
+	change, ok := m.GetUndoStack().PeekRedo()
+	if !ok {
+		m.SetStatusMessage("Nothing to redo")
+		return nil
+	}
+	return startChange(m, "Redoing "+change.Description, "✓ Redid "+change.Description, change, change.Do, Redone)
+}
+
+// startChange applies one side of a change in the background and reloads the courses.
+// The label is shown while it runs and the status once it succeeded. If the course changed
+// while it was applied, the change stays on its stack and the courses are reloaded too.
+func startChange(m DataScreenModel, label, status string, change Change, mutation Mutation, dir UndoDirection) tea.Cmd {
+	if m.GetOperation() != "" {
+		m.SetStatusMessage("Please wait: " + m.GetOperation() + " (Esc to cancel)")
+		return nil
+	}
+
+	store := m.GetStore()
+	uni := m.GetSelectedUniversity()
+	return StartOperation(m, label, func(ctx context.Context) tea.Msg {
+		err := mutation.Apply(ctx, store, uni)
+		if errors.Is(err, api.ErrConflict) {
+			// The course changed between reading and writing it; show what it is now
+			return CommandDoneMsg{
+				University: uni,
+				Status:     fmt.Sprintf("Error: %s failed: %v; reloaded, try again", label, err),
+				Err:        err,
+				Reload:     loadCourses(ctx, store, uni),
+			}
+		}
+		if err != nil && !api.IsWarning(err) {
+			return CommandDoneMsg{University: uni, Status: fmt.Sprintf("Error: %s failed: %v", label, err), Err: err}
+		}
+		return CommandDoneMsg{
+			University: uni,
+			Status:     withWarning(status, err),
+			Reload:     loadCourses(ctx, store, uni),
+			Change:     &change,
+			Direction:  dir,
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package grades_test
+
+import (
+	// Standard library imports
+	"context" // Contexts of the store calls
+	"strings" // Status inspection
+	"testing" // Test framework
+
+	// Internal packages
+	"UniGrades/internal/api"            // Database operations
+	"UniGrades/internal/screens/grades" // Data screen under test
+)
+
+// racingStore is a MemoryStore in which another client edits a course right after it
+// was read, while racing is set.
+type racingStore struct {
+	*api.MemoryStore
+	racing bool
+}
+
+func (s *racingStore) GetCourse(ctx context.Context, uni, id string) (api.Course, error) {
+	course, err := s.MemoryStore.GetCourse(ctx, uni, id)
+	if err == nil && s.racing {
+		s.MemoryStore.UpdateCourse(ctx, uni, id, api.AnyRevision, map[string]string{"ECTS": "6"})
+	}
+	return course, err
+}
+
+// TestUndoConflictKeepsCourses checks that an undo that conflicts with a concurrent edit
+// shows the reloaded courses instead of an empty table, and can be tried again.
+func TestUndoConflictKeepsCourses(t *testing.T) {
+	const uni = "TU/e"
+	ctx := context.Background()
+	inner := &racingStore{MemoryStore: api.NewMemoryStore()}
+	if _, err := inner.AddCourse(ctx, uni, api.Course{Name: "Calculus", Year: 1, Grade: 7, ECTS: 5}); err != nil {
+		t.Fatal(err)
+	}
+	m := openScreen(t, api.WithHistory(inner, api.NewMemoryHistory()), uni)
+
+	m.SetTextInputValue("/edit Calculus Grade=9")
+	grades.HandleCommandDone(m, grades.HandleDataScreenInput(m)().(grades.CommandDoneMsg))
+	if _, ok := m.GetUndoStack().PeekUndo(); !ok {
+		t.Fatalf("edit was not recorded for undo: %s", m.GetStatusMessage())
+	}
+
+	inner.racing = true
+	msg := grades.Undo(m)().(grades.CommandDoneMsg)
+	grades.HandleCommandDone(m, msg)
+
+	if msg.Err == nil {
+		t.Fatal("undo succeeded, want a conflict")
+	}
+	courses := m.GetCourses()
+	if len(courses) != 1 || courses[0].ECTS != 6 {
+		t.Errorf("courses = %+v, want the reloaded course with ECTS 6", courses)
+	}
+	if !strings.Contains(m.GetStatusMessage(), "Error") {
+		t.Errorf("status = %q, want the failed undo", m.GetStatusMessage())
+	}
+	if _, ok := m.GetUndoStack().PeekUndo(); !ok {
+		t.Error("the conflicting change was dropped from the undo stack")
+	}
+	if _, ok := m.GetUndoStack().PeekRedo(); ok {
+		t.Error("the conflicting change was moved to the redo stack")
+	}
+}
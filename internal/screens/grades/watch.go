@@ -0,0 +1,102 @@
+// Package grades provides the data/grades screen for displaying course information and statistics.
+package grades
+
+import (
+	// Standard library imports
+	"context" // Cancellation of the watch
+	"reflect" // Comparison of reloaded courses
+
+	// Bubble Tea framework
+	tea "github.com/charmbracelet/bubbletea"
+
+	// Internal packages
+	"UniGrades/internal/api" // Database operations
+)
+
+// CoursesChangedMsg is sent when the courses of the watched university changed in the store,
+// e.g. through the HTTP API or another instance of the app.
+type CoursesChangedMsg struct {
+	// University is the university whose courses changed
+	University string
+	// events delivers the next change notification of the same watch
+	events <-chan struct{}
+}
+
+// ExternalReloadMsg is sent when the courses have been reloaded after an external change.
+type ExternalReloadMsg struct {
+	// Reload holds the reloaded courses
+	Reload CoursesLoadedMsg
+}
+
+// WatchCourses starts watching the courses of the selected university, replacing any
+// previous watch. Every change made outside this screen is delivered as a CoursesChangedMsg.
+// Changes are pushed by stores that support it and polled every refresh interval otherwise;
+// a zero interval only disables polling. The interval is read once, when the watch starts.
+func WatchCourses(m DataScreenModel) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.SetWatch(cancel)
+
+	// A buffer of one coalesces bursts of changes into a single reload
+	events := make(chan struct{}, 1)
+	store := m.GetStore()
+	uni := m.GetSelectedUniversity()
+	interval := m.GetRefreshInterval()
+	go func() {
+		defer close(events)
+		api.WatchCourses(ctx, store, uni, interval, func() {
+			select {
+			case events <- struct{}{}:
+			default:
+			}
+		})
+	}()
+	return waitForChange(uni, events)
+}
+
+// waitForChange waits for the next change notification of a watch.
+// Once the watch stops, the command finishes without a message.
+func waitForChange(uni string, events <-chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		if _, ok := <-events; !ok {
+			return nil
+		}
+		return CoursesChangedMsg{University: uni, events: events}
+	}
+}
+
+// HandleCoursesChanged reloads the courses after an external change and keeps waiting
+// for the next one. Changes of a university that is no longer selected are ignored.
+func HandleCoursesChanged(m DataScreenModel, msg CoursesChangedMsg) tea.Cmd {
+	uni := m.GetSelectedUniversity()
+	if msg.University != uni {
+		return nil
+	}
+
+	store := m.GetStore()
+	reload := func() tea.Msg {
+		return ExternalReloadMsg{Reload: loadCourses(context.Background(), store, uni)}
+	}
+	return tea.Batch(reload, waitForChange(uni, msg.events))
+}
+
+// HandleExternalReload shows courses reloaded after an external change, with a notice.
+// It leaves the screen alone if nothing visible changed (e.g. the change was this screen's
+// own command), if the reload failed, or while a command runs, since a command reloads
+// the courses itself once it finishes.
+func HandleExternalReload(m DataScreenModel, msg ExternalReloadMsg) {
+	reload := msg.Reload
+	if reload.University != m.GetSelectedUniversity() || m.GetOperation() != "" || reload.Err != nil {
+		return
+	}
+	if m.GetLoadError() == nil && reflect.DeepEqual(reload.Courses, m.GetCourses()) {
+		return
+	}
+
+	m.SetCourses(reload.Courses, reload.Stats, nil)
+	RefreshCharts(m)
+	if reload.Warning != "" {
+		m.SetStatusMessage("↻ Courses updated externally | " + reload.Warning)
+		return
+	}
+	m.SetStatusMessage("↻ Courses updated externally")
+}
@@ -4,17 +4,15 @@ package picker
 
 import (
 	// Standard library imports
-	"fmt" // Formatted I/O
+	"context" // Cancellation of in-flight operations
+	"fmt"     // Formatted I/O
+	"time"    // Refresh interval of the live course watch
 
 	// Bubble Tea components
 	textinput "github.com/charmbracelet/bubbles/textinput" // Text input component
 	tea "github.com/charmbracelet/bubbletea"               // TUI framework
 	"github.com/charmbracelet/lipgloss"                    // Styling and layout
 
-	// MongoDB types
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
-
 	// Internal packages
 	"UniGrades/internal/api"            // Database operations
 	"UniGrades/internal/screens/grades" // Data screen
@@ -47,8 +45,9 @@ type Model struct {
 	EctsStr           string // Rendered total ECTS bar
 
 	// Course data
-	Headers []string // Column headers for the table
-	Courses []bson.M // Course documents from database
+	Columns []api.Column    // Columns of the course table, from the column registry
+	Courses []api.Course    // Courses of the selected university
+	Stats   api.CourseStats // Statistics of the courses, computed by the store
 
 	// Terminal state
 	TermWidth  int // Width of the terminal
@@ -59,40 +58,42 @@ type Model struct {
 	TextInput textinput.Model // Text input component for commands
 
 	// External resources
-	MongoClient   *mongo.Client // MongoDB connection
-	StatusMessage string        // User feedback message
+	Store         api.CourseStore  // Course storage backend
+	History       api.HistoryStore // Change history of the courses, nil if not recorded
+	StatusMessage string           // User feedback message
+	LoadErr       error            // Error from the last course load, shown as an error state
+	PanelStr      string           // Rendered history or trash panel, shown instead of the charts while non-empty
+	UndoStack     grades.UndoStack // Changes of the selected university that can be undone and redone
+
+	// In-flight store operation
+	Operation string             // Label of the running operation, empty when idle
+	cancelOp  context.CancelFunc // Cancels the running operation
+
+	// Live refresh of the selected university's courses
+	RefreshInterval time.Duration      // How often to poll for external changes when the store can't push them; 0 disables polling
+	cancelWatch     context.CancelFunc // Stops watching the courses
 }
 
 // InitialModel creates and returns a new Model with initial state.
-func InitialModel(headers []string, courses []bson.M, client *mongo.Client) Model {
+// Course data is loaded from the store once a university is selected;
+// the history store backs the /history command.
+func InitialModel(store api.CourseStore, history api.HistoryStore) Model {
 	// Initialize text input for commands
 	ti := textinput.New()
-	ti.Placeholder = "Commands: /add Name Year Grade ECTS | /edit Name Field Value | /delete Name"
+	ti.Placeholder = "Commands: /add Name Year Grade ECTS | /edit Name|ID Field=Value ... | /delete Name|ID | /trash"
 	ti.Focus()
 
-	// Render all initial displays
-	tableStr := tui.RenderTable(tui.DefaultColor, headers, courses)
-	avgStr := tui.RenderAverageGrades(tui.DefaultColor, courses)
-	avgPerYearStr := tui.RenderAverageGradesPerYear(tui.DefaultColor, courses)
-	avgECTSPerYearStr := tui.RenderTotalECTSPerYear(tui.DefaultColor, courses)
-	ectsStr := tui.RenderECTS(tui.DefaultColor, courses)
-
 	return Model{
-		Choices:           university.Names(),
-		Selected:          make(map[int]struct{}),
-		TableStr:          tableStr,
-		AvgStr:            avgStr,
-		AvgPerYearStr:     avgPerYearStr,
-		AvgECTSPerYearStr: avgECTSPerYearStr,
-		EctsStr:           ectsStr,
-		Headers:           headers,
-		Courses:           courses,
-		TermWidth:         80,
-		TermHeight:        24,
-		Screen:            PickerScreen,
-		TextInput:         ti,
-		MongoClient:       client,
-		StatusMessage:     "",
+		Choices:       university.Names(),
+		Selected:      make(map[int]struct{}),
+		TermWidth:     80,
+		TermHeight:    24,
+		Screen:        PickerScreen,
+		TextInput:     ti,
+		Store:         store,
+		History:       history,
+		Columns:       api.DefaultColumns(),
+		StatusMessage: "",
 	}
 }
 
@@ -104,6 +105,37 @@ func (m Model) Init() tea.Cmd {
 // Update handles incoming messages and updates model state accordingly.
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case grades.CoursesLoadedMsg:
+		// Courses finished loading: open the data screen on success and start
+		// watching the courses for changes made elsewhere
+		if grades.HandleCoursesLoaded(&m, msg) && m.Screen == PickerScreen {
+			m.Screen = DataScreen
+			return m, grades.WatchCourses(&m)
+		}
+		return m, nil
+	case grades.CoursesChangedMsg:
+		// The courses changed outside this screen: reload them
+		return m, grades.HandleCoursesChanged(&m, msg)
+	case grades.ExternalReloadMsg:
+		// The courses reloaded after an external change arrived
+		grades.HandleExternalReload(&m, msg)
+		return m, nil
+	case grades.CommandDoneMsg:
+		// A data screen command finished
+		grades.HandleCommandDone(&m, msg)
+		return m, nil
+	case grades.HistoryLoadedMsg:
+		// The history requested with /history arrived
+		grades.HandleHistoryLoaded(&m, msg)
+		return m, nil
+	case grades.SyncDoneMsg:
+		// /sync finished replaying the changes made offline
+		grades.HandleSyncDone(&m, msg)
+		return m, nil
+	case grades.TrashLoadedMsg:
+		// The trashed courses requested with /trash arrived
+		grades.HandleTrashLoaded(&m, msg)
+		return m, nil
 	case tea.WindowSizeMsg:
 		// Update terminal dimensions when window is resized
 		m.TermWidth = msg.Width
@@ -115,16 +147,46 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Quit application
 			return m, tea.Quit
 
+		case "esc":
+			// Cancel the in-flight store operation, if any, or close the history or trash panel
+			if grades.CancelOperation(&m) || grades.ClosePanel(&m) {
+				return m, nil
+			}
+
 		case "ctrl+q":
 			// Return to picker screen from data screen
 			if m.Screen == DataScreen {
+				m.CancelOperation()
+				m.SetWatch(nil)
+				m.Operation = ""
 				m.Screen = PickerScreen
 				m.Selected = make(map[int]struct{})
 				m.TextInput.SetValue("")
 				m.StatusMessage = ""
+				m.LoadErr = nil
+				m.PanelStr = ""
+				m.UndoStack.Clear()
 				return m, nil
 			}
 
+		case "ctrl+z":
+			// Undo the most recent change on the data screen
+			if m.Screen == DataScreen {
+				return m, grades.Undo(&m)
+			}
+
+		case "ctrl+y":
+			// Redo the most recently undone change on the data screen
+			if m.Screen == DataScreen {
+				return m, grades.Redo(&m)
+			}
+
+		case "ctrl+r":
+			// Retry loading the courses after an error on the data screen
+			if m.Screen == DataScreen {
+				return m, grades.RetryLoad(&m)
+			}
+
 		case "up", "k":
 			// Navigate up in picker screen
 			if m.Screen == PickerScreen {
@@ -144,31 +206,23 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "enter":
-			// Toggle selection in picker screen, or handle commands in data screen
+			// Select a university in picker screen, or handle commands in data screen
 			if m.Screen == PickerScreen {
-				_, ok := m.Selected[m.Cursor]
-				if ok {
-					// Deselect the university
-					delete(m.Selected, m.Cursor)
-					m.TableStr = tui.RenderTable(tui.DefaultColor, m.Headers, m.Courses)
-					m.AvgStr = tui.RenderAverageGrades(tui.DefaultColor, m.Courses)
-					m.AvgPerYearStr = tui.RenderAverageGradesPerYear(tui.DefaultColor, m.Courses)
-					m.AvgECTSPerYearStr = tui.RenderTotalECTSPerYear(tui.DefaultColor, m.Courses)
-					m.EctsStr = tui.RenderECTS(tui.DefaultColor, m.Courses)
-				} else {
-					// Select the university
-					m.Selected = map[int]struct{}{m.Cursor: {}}
-					color := uniColors[m.Choices[m.Cursor]]
-					m.TableStr = tui.RenderTable(color, m.Headers, m.Courses)
-					m.AvgStr = tui.RenderAverageGrades(color, m.Courses)
-					m.AvgPerYearStr = tui.RenderAverageGradesPerYear(color, m.Courses)
-					m.AvgECTSPerYearStr = tui.RenderTotalECTSPerYear(color, m.Courses)
-					m.EctsStr = tui.RenderECTS(color, m.Courses)
-					m.Screen = DataScreen
+				// Select the university and start loading its courses from the store.
+				// The data screen opens once they arrive; on failure, the picker shows
+				// the error and Enter retries.
+				if m.Operation != "" {
+					return m, nil
 				}
+				m.Selected = map[int]struct{}{m.Cursor: {}}
+				m.LoadErr = nil
+				return m, grades.LoadCourses(&m)
 			} else if m.Screen == DataScreen {
 				// Handle text input commands on data screen
-				grades.HandleDataScreenInput(&m)
+				cmd := grades.HandleDataScreenInput(&m)
+				var inputCmd tea.Cmd
+				m.TextInput, inputCmd = m.TextInput.Update(msg)
+				return m, tea.Batch(cmd, inputCmd)
 			}
 		}
 
@@ -194,9 +248,9 @@ func (m Model) SelectedUniversity() string {
 // Interface implementation methods for grades.DataScreenModel
 // These methods allow the data screen to access model state without circular imports.
 
-// GetMongoClient returns the MongoDB client.
-func (m Model) GetMongoClient() *mongo.Client {
-	return m.MongoClient
+// GetStore returns the course storage backend.
+func (m Model) GetStore() api.CourseStore {
+	return m.Store
 }
 
 // GetSelectedUniversity returns the selected university name.
@@ -249,34 +303,70 @@ func (m *Model) SetStatusMessage(msg string) {
 	m.StatusMessage = msg
 }
 
-// RefreshCourses refreshes the courses from the database.
-func (m *Model) RefreshCourses() {
-	m.Courses = api.GetAllCourses(m.MongoClient)
+// GetCourseCount returns the number of courses loaded for the selected university.
+func (m Model) GetCourseCount() int {
+	return len(m.Courses)
+}
+
+// GetLoadError returns the error from the last course load, or nil if it succeeded.
+func (m Model) GetLoadError() error {
+	return m.LoadErr
+}
+
+// SetCourses stores freshly loaded courses and their statistics.
+// On a load error the previously loaded data is kept and the error is stored in LoadErr.
+func (m *Model) SetCourses(courses []api.Course, stats api.CourseStats, err error) {
+	m.LoadErr = err
+	if err != nil {
+		return
+	}
+	m.Courses = courses
+	m.Stats = stats
+}
+
+// GetOperation returns the label of the in-flight store operation, or empty string if idle.
+func (m Model) GetOperation() string {
+	return m.Operation
+}
+
+// SetOperation records the in-flight store operation and the function that cancels it.
+// An empty label marks the model as idle.
+func (m *Model) SetOperation(label string, cancel context.CancelFunc) {
+	m.Operation = label
+	m.cancelOp = cancel
+}
+
+// CancelOperation cancels the in-flight store operation, if any.
+func (m *Model) CancelOperation() {
+	if m.cancelOp != nil {
+		m.cancelOp()
+		m.cancelOp = nil
+	}
 }
 
 // RefreshTableStr refreshes the table string with the given color.
 func (m *Model) RefreshTableStr(color lipgloss.Color) {
-	m.TableStr = tui.RenderTable(color, m.Headers, m.Courses)
+	m.TableStr = tui.RenderTable(color, m.Columns, m.Courses)
 }
 
 // RefreshAvgStr refreshes the average grades string with the given color.
 func (m *Model) RefreshAvgStr(color lipgloss.Color) {
-	m.AvgStr = tui.RenderAverageGrades(color, m.Courses)
+	m.AvgStr = tui.RenderAverageGrades(color, m.Stats)
 }
 
 // RefreshAvgPerYearStr refreshes the average grades per year string with the given color.
 func (m *Model) RefreshAvgPerYearStr(color lipgloss.Color) {
-	m.AvgPerYearStr = tui.RenderAverageGradesPerYear(color, m.Courses)
+	m.AvgPerYearStr = tui.RenderAverageGradesPerYear(color, m.Stats)
 }
 
 // RefreshAvgECTSPerYearStr refreshes the average ECTS per year string with the given color.
 func (m *Model) RefreshAvgECTSPerYearStr(color lipgloss.Color) {
-	m.AvgECTSPerYearStr = tui.RenderTotalECTSPerYear(color, m.Courses)
+	m.AvgECTSPerYearStr = tui.RenderTotalECTSPerYear(color, m.Stats)
 }
 
 // RefreshEctsStr refreshes the ECTS string with the given color.
 func (m *Model) RefreshEctsStr(color lipgloss.Color) {
-	m.EctsStr = tui.RenderECTS(color, m.Courses)
+	m.EctsStr = tui.RenderECTS(color, m.Stats)
 }
 
 // GetTextInputValue returns the current text input value.
@@ -289,6 +379,50 @@ func (m *Model) SetTextInputValue(value string) {
 	m.TextInput.SetValue(value)
 }
 
+// GetUndoStack returns the changes that can be undone and redone.
+func (m *Model) GetUndoStack() *grades.UndoStack {
+	return &m.UndoStack
+}
+
+// GetHistoryStore returns the course history store, or nil if history is not recorded.
+func (m Model) GetHistoryStore() api.HistoryStore {
+	return m.History
+}
+
+// GetPanelStr returns the rendered history or trash panel, or empty string if none is open.
+func (m Model) GetPanelStr() string {
+	return m.PanelStr
+}
+
+// SetPanelStr sets the rendered panel shown instead of the charts; an empty string closes it.
+func (m *Model) SetPanelStr(panel string) {
+	m.PanelStr = panel
+}
+
+// GetCourses returns the courses loaded for the selected university.
+func (m Model) GetCourses() []api.Course {
+	return m.Courses
+}
+
+// GetColumns returns the columns the course table is rendered in.
+func (m Model) GetColumns() []api.Column {
+	return m.Columns
+}
+
+// GetRefreshInterval returns how often to poll for external course changes.
+func (m Model) GetRefreshInterval() time.Duration {
+	return m.RefreshInterval
+}
+
+// SetWatch records the function that stops the running course watch, stopping
+// the previous watch first. A nil function only stops the previous watch.
+func (m *Model) SetWatch(cancel context.CancelFunc) {
+	if m.cancelWatch != nil {
+		m.cancelWatch()
+	}
+	m.cancelWatch = cancel
+}
+
 // Module-level variable holding the university color map.
 var uniColors = university.ColorMap()
 
@@ -321,6 +455,15 @@ func (m Model) renderPickerScreen() string {
 		s += fmt.Sprintf("%s [%s] %s\n", cursor, checked, style.Render(choice))
 	}
 
+	// Show progress of a running load, or why the last selection could not be opened
+	if m.Operation != "" {
+		s += "\n" + m.Operation + "... (Esc to cancel)\n"
+	} else if m.LoadErr != nil {
+		errStyle := lipgloss.NewStyle().Foreground(grades.ColorError)
+		s += "\n" + errStyle.Render(fmt.Sprintf("Could not load courses for %s: %v", m.SelectedUniversity(), m.LoadErr))
+		s += "\n" + errStyle.Render("Press Enter to retry.") + "\n"
+	}
+
 	s += "\nPress Ctrl + C to quit."
 
 	// Center the entire picker screen
@@ -3,17 +3,13 @@ package tui
 
 import (
 	// Internal packages
-	"UniGrades/internal/computations"
+	"UniGrades/internal/api" // Course statistics
 	// Standard library imports
-	"fmt"  // Formatted I/O
-	"sort" // Sorting utilities
+	"fmt" // Formatted I/O
 
 	// TUI libraries
 	"github.com/NimbleMarkets/ntcharts/barchart" // Bar chart component
 	"github.com/charmbracelet/lipgloss"          // Styling and layout
-
-	// MongoDB types
-	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 // Constants for the average grades per year bar chart.
@@ -48,24 +44,19 @@ func barStyleForYear(index int) lipgloss.Style {
 // Parameters:
 //
 //	uniColor: The university brand color for box styling
-//	courses: The course documents to analyze
+//	stats: The course statistics computed by the store
 //
 // Returns:
 //
 //	A formatted string with the chart and statistics
-func RenderAverageGradesPerYear(uniColor lipgloss.Color, courses []bson.M) string {
-	// Parse grades and years from courses
-	grades, years := computations.ParseGradesAndYears(courses)
-
-	// Calculate average grade for each year
-	avgPerYear := computations.AverageGradePerYear(grades, years)
-
-	// Sort years for consistent display order
-	sortedYears := make([]int, 0, len(avgPerYear))
-	for y := range avgPerYear {
-		sortedYears = append(sortedYears, y)
+func RenderAverageGradesPerYear(uniColor lipgloss.Color, stats api.CourseStats) string {
+	// Collect the average grade of each year; the store lists the years in ascending order
+	sortedYears := make([]int, 0, len(stats.Years))
+	avgPerYear := make(map[int]float64, len(stats.Years))
+	for _, y := range stats.Years {
+		sortedYears = append(sortedYears, y.Year)
+		avgPerYear[y.Year] = y.AverageGrade
 	}
-	sort.Ints(sortedYears)
 
 	// Build bar chart data
 	barData := BuildBarDataPerYear(sortedYears, avgPerYear)
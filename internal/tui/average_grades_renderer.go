@@ -3,17 +3,13 @@ package tui
 
 import (
 	// Internal packages
-	"UniGrades/internal/computations"
+	"UniGrades/internal/api" // Course statistics
 	// Standard library imports
-	"fmt"     // Formatted I/O
-	"strconv" // String conversion
+	"fmt" // Formatted I/O
 
 	// TUI libraries
 	"github.com/charmbracelet/lipgloss"       // Styling and layout
 	"github.com/charmbracelet/lipgloss/table" // Table component
-
-	// MongoDB types
-	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 // RenderAverageGrades displays overall grade statistics.
@@ -22,42 +18,17 @@ import (
 // Parameters:
 //
 //	uniColor: The university brand color for table styling
-//	courses: The course documents to analyze
+//	stats: The course statistics computed by the store
 //
 // Returns:
 //
 //	A formatted table string with average grade metrics
-func RenderAverageGrades(uniColor lipgloss.Color, courses []bson.M) string {
-	var grades []float64
-	var ects []float64
-
-	// Extract grades and ECTS from all courses
-	for _, course := range courses {
-		gradeStr := fmt.Sprintf("%v", course["Grade"])
-		ectsStr := fmt.Sprintf("%v", course["ECTS"])
-
-		grade, err := strconv.ParseFloat(gradeStr, 64)
-		if err != nil {
-			continue // Skip courses with invalid grade
-		}
-		credit, err := strconv.ParseFloat(ectsStr, 64)
-		if err != nil {
-			continue // Skip courses with invalid ECTS
-		}
-
-		grades = append(grades, grade)
-		ects = append(ects, credit)
-	}
-
-	// Calculate both averages
-	avg := computations.Average(grades)
-	weightedAvg := computations.WeightedAverage(grades, ects)
-
-	// Create table with results
+func RenderAverageGrades(uniColor lipgloss.Color, stats api.CourseStats) string {
+	// Create table with both averages
 	headers := []string{"Metric", "Value"}
 	rows := [][]string{
-		{"Average Grade", fmt.Sprintf("%.2f", avg)},
-		{"Weighted Average (ECTS)", fmt.Sprintf("%.2f", weightedAvg)},
+		{"Average Grade", fmt.Sprintf("%.2f", stats.AverageGrade)},
+		{"Weighted Average (ECTS)", fmt.Sprintf("%.2f", stats.WeightedAverage)},
 	}
 
 	t := table.New().
@@ -0,0 +1,65 @@
+// Package tui provides terminal user interface rendering components for UniGrades.
+package tui
+
+import (
+	// Internal packages
+	"UniGrades/internal/api" // History entries
+
+	// Standard library imports
+	"fmt"     // Formatted I/O and string conversion
+	"strings" // String manipulation
+
+	// TUI libraries
+	"github.com/charmbracelet/lipgloss"       // Styling and layout
+	"github.com/charmbracelet/lipgloss/table" // Table component
+)
+
+// HistoryMaxRows is the number of most recent history entries shown in the history table.
+const HistoryMaxRows = 15
+
+// RenderHistory creates a table of course history entries, newest first,
+// with a border styled in the university color. Only the HistoryMaxRows most recent
+// entries are shown; a caption below the table tells how many were left out.
+//
+// Parameters:
+//
+//	uniColor: The university brand color for table borders
+//	title: The caption shown above the table (e.g. "History of Applied_Math")
+//	entries: The history entries to display, newest first
+//
+// Returns:
+//
+//	A formatted table string
+func RenderHistory(uniColor lipgloss.Color, title string, entries []api.HistoryEntry) string {
+	shown := entries
+	if len(shown) > HistoryMaxRows {
+		shown = shown[:HistoryMaxRows]
+	}
+
+	// Convert each entry to a row of strings
+	rows := make([][]string, 0, len(shown))
+	for _, e := range shown {
+		course := e.Course()
+		rows = append(rows, []string{
+			e.Time.Local().Format("2006-01-02 15:04"),
+			string(e.Operation),
+			course.ShortID(),
+			course.Name,
+			strings.Join(e.Changes(), ", "),
+		})
+	}
+
+	// Create and configure the table
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(uniColor)).
+		StyleFunc(TableStyleFunc(uniColor)).
+		Headers("Time", "Action", "ID", "Course", "Changes").
+		Rows(rows...)
+
+	s := title + "\n" + t.Render()
+	if len(entries) > len(shown) {
+		s += fmt.Sprintf("\nShowing the %d most recent of %d entries.", len(shown), len(entries))
+	}
+	return s
+}
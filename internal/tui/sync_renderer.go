@@ -0,0 +1,69 @@
+// Package tui provides terminal user interface rendering components for UniGrades.
+package tui
+
+import (
+	// Internal packages
+	"UniGrades/internal/api" // Sync status model
+
+	// Standard library imports
+	"strings" // String building
+
+	// TUI libraries
+	"github.com/charmbracelet/lipgloss"       // Styling and layout
+	"github.com/charmbracelet/lipgloss/table" // Table component
+)
+
+// RenderSyncStatus creates the tables of the changes made offline that are still waiting
+// to be synced and of those that conflicted with the server, with borders styled in the
+// university color. Empty tables are left out.
+//
+// Parameters:
+//
+//	uniColor: The university brand color for table borders
+//	status: The sync status to display
+//
+// Returns:
+//
+//	A formatted string with a caption above each table
+func RenderSyncStatus(uniColor lipgloss.Color, status api.SyncStatus) string {
+	var sections []string
+
+	if len(status.Pending) > 0 {
+		rows := make([][]string, 0, len(status.Pending))
+		for _, e := range status.Pending {
+			rows = append(rows, []string{
+				e.Time.Local().Format("2006-01-02 15:04"),
+				e.University,
+				e.Description(),
+			})
+		}
+		t := table.New().
+			Border(lipgloss.NormalBorder()).
+			BorderStyle(lipgloss.NewStyle().Foreground(uniColor)).
+			StyleFunc(TableStyleFunc(uniColor)).
+			Headers("Made", "University", "Change").
+			Rows(rows...)
+		sections = append(sections, "Waiting to sync\n"+t.Render())
+	}
+
+	if len(status.Conflicts) > 0 {
+		rows := make([][]string, 0, len(status.Conflicts))
+		for _, c := range status.Conflicts {
+			rows = append(rows, []string{
+				c.Entry.Time.Local().Format("2006-01-02 15:04"),
+				c.Entry.University,
+				c.Entry.Description(),
+				c.Reason,
+			})
+		}
+		t := table.New().
+			Border(lipgloss.NormalBorder()).
+			BorderStyle(lipgloss.NewStyle().Foreground(uniColor)).
+			StyleFunc(TableStyleFunc(uniColor)).
+			Headers("Made", "University", "Change", "Not applied because").
+			Rows(rows...)
+		sections = append(sections, "Conflicts with the server\n"+t.Render())
+	}
+
+	return strings.Join(sections, "\n\n")
+}
@@ -2,84 +2,65 @@
 package tui
 
 import (
+	// Internal packages
+	"UniGrades/internal/api" // Course model
+
 	// Standard library imports
-	"fmt"     // Formatted I/O and string conversion
-	"sort"    // Sorting utilities
-	"strconv" // String conversion utilities
+	"sort" // Sorting utilities
 
 	// TUI libraries
 	"github.com/charmbracelet/lipgloss"       // Styling and layout
 	"github.com/charmbracelet/lipgloss/table" // Table component
-
-	// MongoDB types
-	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
-// sortCoursesByYear sorts a slice of course documents by their year field in ascending order.
-// A copy is made to avoid modifying the original slice.
-func sortCoursesByYear(courses []bson.M) []bson.M {
-	sorted := make([]bson.M, len(courses))
+// sortCoursesByYear sorts a slice of courses by their year in ascending order.
+// A copy is made to avoid modifying the original slice; courses within a year keep their order.
+func sortCoursesByYear(courses []api.Course) []api.Course {
+	sorted := make([]api.Course, len(courses))
 	copy(sorted, courses)
-	sort.Slice(sorted, func(i, j int) bool {
-		yearI := toYear(sorted[i]["Year"])
-		yearJ := toYear(sorted[j]["Year"])
-		return yearI < yearJ
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Year < sorted[j].Year
 	})
 	return sorted
 }
 
-// toYear converts various numeric types to int for year comparison.
-// Handles int, int32, int64, float64, and string types.
-func toYear(v interface{}) int {
-	switch val := v.(type) {
-	case int:
-		return val
-	case int32:
-		return int(val)
-	case int64:
-		return int(val)
-	case float64:
-		return int(val)
-	case string:
-		year, _ := strconv.Atoi(val)
-		return year
-	default:
-		return 0
-	}
-}
-
 // RenderTable creates a formatted table displaying courses with a border styled in the university color.
-// Courses are sorted by year, and fields are displayed in the order of the provided headers.
+// Trashed courses are left out. The rest are sorted by year, and rendered in the given
+// columns of the column registry, each formatted and aligned as its entry says.
+// The ID column is always shown first, so that courses sharing a name can be told apart
+// and targeted by commands.
 //
 // Parameters:
 //
 //	uniColor: The university brand color for table borders
-//	headers: The column headers to display
-//	courses: The course documents to display
+//	columns: The columns to display, e.g. api.DefaultColumns()
+//	courses: The courses to display
 //
 // Returns:
 //
 //	A formatted table string
-func RenderTable(uniColor lipgloss.Color, headers []string, courses []bson.M) string {
-	// Sort courses by year so they appear in chronological order
-	courses = sortCoursesByYear(courses)
+func RenderTable(uniColor lipgloss.Color, columns []api.Column, courses []api.Course) string {
+	// Sort active courses by year so they appear in chronological order
+	courses = sortCoursesByYear(api.ActiveCourses(courses))
+
+	// Lead with the ID column
+	if len(columns) == 0 || columns[0].Key != "ID" {
+		id, _ := api.LookupColumn("ID")
+		columns = append([]api.Column{id}, columns...)
+	}
 
 	// Convert each course to a row of strings
 	rows := make([][]string, 0, len(courses))
 	for _, course := range courses {
-		row := make([]string, 0, len(headers))
-		for _, h := range headers {
-			row = append(row, fmt.Sprintf("%v", course[h]))
-		}
-		rows = append(rows, row)
+		rows = append(rows, api.FormatRow(columns, course))
 	}
 
 	// Create and configure the table
 	t := table.New().
 		Border(lipgloss.NormalBorder()).
 		BorderStyle(lipgloss.NewStyle().Foreground(uniColor)).
-		StyleFunc(TableStyleFunc(uniColor)).
-		Headers(headers...).
+		StyleFunc(ColumnStyleFunc(uniColor, columns)).
+		Headers(api.Labels(columns)...).
 		Rows(rows...)
 
 	return t.Render()
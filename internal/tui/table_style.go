@@ -2,6 +2,9 @@
 package tui
 
 import (
+	// Internal packages
+	"UniGrades/internal/api" // Column registry
+
 	// TUI libraries
 	"github.com/charmbracelet/lipgloss"       // Styling and layout
 	"github.com/charmbracelet/lipgloss/table" // Table component
@@ -42,3 +45,17 @@ func TableStyleFunc(uniColor lipgloss.Color) func(row, col int) lipgloss.Style {
 		}
 	}
 }
+
+// ColumnStyleFunc returns a style function for tables of courses that aligns the values
+// of each column as its registry entry says, on top of the styling of TableStyleFunc.
+// uniColor is the university brand color used for header styling.
+func ColumnStyleFunc(uniColor lipgloss.Color, columns []api.Column) func(row, col int) lipgloss.Style {
+	base := TableStyleFunc(uniColor)
+	return func(row, col int) lipgloss.Style {
+		style := base(row, col)
+		if row != table.HeaderRow && col < len(columns) && columns[col].Align == api.AlignRight {
+			return style.Align(lipgloss.Right)
+		}
+		return style
+	}
+}
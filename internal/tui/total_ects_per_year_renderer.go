@@ -3,17 +3,13 @@ package tui
 
 import (
 	// Internal packages
-	"UniGrades/internal/computations"
+	"UniGrades/internal/api" // Course statistics
 	// Standard library imports
-	"fmt"  // Formatted I/O
-	"sort" // Sorting utilities
+	"fmt" // Formatted I/O
 
 	// TUI libraries
 	"github.com/NimbleMarkets/ntcharts/barchart" // Bar chart component
 	"github.com/charmbracelet/lipgloss"          // Styling and layout
-
-	// MongoDB types
-	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 // Constants for the total ECTS per year bar chart.
@@ -32,24 +28,19 @@ const (
 // Parameters:
 //
 //	uniColor: The university brand color for box styling
-//	courses: The course documents to analyze
+//	stats: The course statistics computed by the store
 //
 // Returns:
 //
 //	A formatted string with the chart and statistics
-func RenderTotalECTSPerYear(uniColor lipgloss.Color, courses []bson.M) string {
-	// Parse ECTS and years from courses
-	ects, years := computations.ParseECTSAndYears(courses)
-
-	// Calculate total ECTS for each year
-	totalPerYear := computations.TotalECTSPerYear(ects, years)
-
-	// Sort years for consistent display order
-	sortedYears := make([]int, 0, len(totalPerYear))
-	for y := range totalPerYear {
-		sortedYears = append(sortedYears, y)
+func RenderTotalECTSPerYear(uniColor lipgloss.Color, stats api.CourseStats) string {
+	// Collect the total ECTS of each year; the store lists the years in ascending order
+	sortedYears := make([]int, 0, len(stats.Years))
+	totalPerYear := make(map[int]float64, len(stats.Years))
+	for _, y := range stats.Years {
+		sortedYears = append(sortedYears, y.Year)
+		totalPerYear[y.Year] = y.TotalECTS
 	}
-	sort.Ints(sortedYears)
 
 	// Build bar chart data
 	barData := BuildBarDataPerYear(sortedYears, totalPerYear)
@@ -3,7 +3,7 @@ package tui
 
 import (
 	// Internal packages
-	"UniGrades/internal/computations"
+	"UniGrades/internal/api" // Course statistics
 	// Standard library imports
 	"fmt"     // Formatted I/O
 	"strings" // String manipulation
@@ -11,9 +11,6 @@ import (
 	// TUI libraries
 	"github.com/NimbleMarkets/ntcharts/barchart" // Bar chart component
 	"github.com/charmbracelet/lipgloss"          // Styling and layout
-
-	// MongoDB types
-	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 // RenderECTS displays a horizontal progress bar showing earned vs remaining ECTS credits.
@@ -22,14 +19,14 @@ import (
 // Parameters:
 //
 //	uniColor: The university brand color for bar styling
-//	courses: The course documents to analyze
+//	stats: The course statistics computed by the store
 //
 // Returns:
 //
 //	A formatted string with the ECTS progress bar and scale
-func RenderECTS(uniColor lipgloss.Color, courses []bson.M) string {
-	// Calculate total earned ECTS
-	totalECTS := computations.TotalECTS(computations.ParseECTS(courses))
+func RenderECTS(uniColor lipgloss.Color, stats api.CourseStats) string {
+	// Total earned ECTS
+	totalECTS := stats.TotalECTS
 
 	// Calculate remaining ECTS to reach 180 (capped at 0 if exceeded)
 	remaining := ECTSMaxValue - totalECTS
@@ -41,8 +38,8 @@ func RenderECTS(uniColor lipgloss.Color, courses []bson.M) string {
 	d1 := barchart.BarData{
 		Label: "ECTS",
 		Values: []barchart.BarValue{
-			{"ECTS", totalECTS, ECTSBarStyle(uniColor)},
-			{"Remaining", remaining, ECTSRemainingStyle()},
+			{Name: "ECTS", Value: totalECTS, Style: ECTSBarStyle(uniColor)},
+			{Name: "Remaining", Value: remaining, Style: ECTSRemainingStyle()},
 		},
 	}
 
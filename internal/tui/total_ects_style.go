@@ -2,6 +2,9 @@
 package tui
 
 import (
+	// Internal packages
+	"UniGrades/internal/api" // Degree requirement
+
 	// TUI libraries
 	"github.com/charmbracelet/lipgloss"
 )
@@ -9,7 +12,7 @@ import (
 // ECTS constants for the total ECTS bar visualization.
 const (
 	// ECTSMaxValue is the maximum ECTS value (180 credits for most bachelor's programs)
-	ECTSMaxValue = 180.0
+	ECTSMaxValue = api.DegreeECTS
 	// ECTSBarWidth is the width of the horizontal ECTS progress bar in characters
 	ECTSBarWidth = 44.0
 	// ECTSBarHeight is the height of the ECTS bar (always 1 for horizontal bars)
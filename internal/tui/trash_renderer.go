@@ -0,0 +1,71 @@
+// Package tui provides terminal user interface rendering components for UniGrades.
+package tui
+
+import (
+	// Internal packages
+	"UniGrades/internal/api" // Course model
+
+	// Standard library imports
+	"sort" // Sorting utilities
+
+	// TUI libraries
+	"github.com/charmbracelet/lipgloss"       // Styling and layout
+	"github.com/charmbracelet/lipgloss/table" // Table component
+)
+
+// RenderTrash creates a table of the trashed courses, most recently deleted first,
+// with a border styled in the university color. The courses are shown in the same
+// columns as the course table, followed by the time they were deleted.
+//
+// Parameters:
+//
+//	uniColor: The university brand color for table borders
+//	title: The caption shown above the table (e.g. "Trash of TU/e")
+//	columns: The columns of the course table
+//	courses: The trashed courses to display
+//
+// Returns:
+//
+//	A formatted table string
+func RenderTrash(uniColor lipgloss.Color, title string, columns []api.Column, courses []api.Course) string {
+	// Sort a copy so the most recently deleted course comes first
+	sorted := make([]api.Course, 0, len(courses))
+	for _, c := range courses {
+		if c.Trashed() {
+			sorted = append(sorted, c)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].DeletedAt.After(*sorted[j].DeletedAt)
+	})
+
+	// Lead with the ID column and end with the deletion time
+	shown := make([]api.Column, 0, len(columns)+2)
+	if len(columns) == 0 || columns[0].Key != "ID" {
+		id, _ := api.LookupColumn("ID")
+		shown = append(shown, id)
+	}
+	deleted, _ := api.LookupColumn("Deleted")
+	for _, col := range columns {
+		if col.Key != deleted.Key {
+			shown = append(shown, col)
+		}
+	}
+	shown = append(shown, deleted)
+
+	// Convert each course to a row of strings
+	rows := make([][]string, 0, len(sorted))
+	for _, c := range sorted {
+		rows = append(rows, api.FormatRow(shown, c))
+	}
+
+	// Create and configure the table
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(uniColor)).
+		StyleFunc(ColumnStyleFunc(uniColor, shown)).
+		Headers(api.Labels(shown)...).
+		Rows(rows...)
+
+	return title + "\n" + t.Render()
+}
@@ -1,17 +1,23 @@
 // Package main is the entry point for the UniGrades application.
-// It initializes the MongoDB connection and launches the terminal UI.
+// It initializes the course store and launches the terminal UI, or runs the
+// backup, restore, export, serve or token command given after the flags.
 package main
 
 import (
 	// Standard library imports for utility functions
-	"fmt" // Formatted I/O
-	"log" // Logging support
-	"os"  // Operating system operations
+	"context" // Background context for startup housekeeping
+	"errors"  // Error inspection
+	"flag"    // Command-line flag parsing
+	"fmt"     // Formatted I/O
+	"log"     // Logging support
+	"os"      // Operating system operations
+	"time"    // Durations for operation timeouts
 
 	// Internal packages for application functionality
-	"UniGrades/internal/api"            // MongoDB database operations
+	"UniGrades/internal/api"            // Course storage operations
 	"UniGrades/internal/screens/picker" // University picker screen
 	"UniGrades/internal/tui"            // Terminal UI rendering
+	"UniGrades/internal/university"     // University names
 
 	// Third-party packages
 	tea "github.com/charmbracelet/bubbletea"       // TUI framework
@@ -20,37 +26,378 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options" // MongoDB options
 )
 
-// main initializes the application, sets up the MongoDB connection,
+// main initializes the application, sets up the course store,
 // and launches the terminal UI with the university picker screen.
 func main() {
-	// Display the application title/banner
-	fmt.Println(tui.RenderTitle())
+	// Parse command-line flags
+	storeKind := flag.String("store", "", "course storage backend: mongo, file or memory (default: mongo if MONGODB_URI is set, otherwise file)")
+	filePath := flag.String("file", "", "path of the data file used by the file store (default: in the user config directory)")
+	defaults := api.DefaultTimeouts()
+	loadTimeout := flag.Duration("load-timeout", defaults.Load, "maximum duration of loading courses (0 disables the timeout)")
+	writeTimeout := flag.Duration("write-timeout", defaults.Add, "maximum duration of adding, editing or deleting a course (0 disables the timeout)")
+	trashRetention := flag.Duration("trash-retention", 30*24*time.Hour, "how long deleted courses stay in the trash before they are purged (0 keeps them forever)")
+	refreshInterval := flag.Duration("refresh-interval", 5*time.Second, "how often to check for courses changed elsewhere when the store can't push changes (0 disables polling)")
+	offline := flag.Bool("offline", true, "keep a local copy of MongoDB courses and queue changes while the database is unreachable")
+	cachePath := flag.String("offline-cache", "", "path of the offline cache of MongoDB courses (default: in the user config directory)")
+	migrateDryRun := flag.Bool("migrate-dry-run", false, "report the pending schema migrations without applying them, then exit")
+	keyFile := flag.String("key-file", "", "encrypt grades with a key derived from this file (or set UNIGRADES_PASSPHRASE)")
+	serveAddr := flag.String("serve", "", "also serve the HTTP API at this address while the terminal UI runs, e.g. "+defaultAddr)
+	columnList := flag.String("columns", "", "comma-separated columns of the course table, e.g. Name,Year,Grade,ECTS,Points (default: Name,Year,Grade,ECTS)")
+	flag.Parse()
+
+	// Pick the columns of the course table from the column registry
+	columns, err := api.ParseColumns(*columnList)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Load environment variables from .env file
 	godotenv.Load(".env")
 
-	// Retrieve MongoDB connection URI from environment
-	uri := os.Getenv("MONGODB_URI")
-	if uri == "" {
-		log.Fatal("Set your 'MONGODB_URI' environment variable. " +
-			"See: www.mongodb.com/docs/drivers/go/current/" +
-			"usage-examples/#environment-variable")
+	// Create the course store selected on the command line; every change is recorded in its history
+	base, history, tokens, closeStore := openStore(*storeKind, *filePath)
+	defer closeStore()
+
+	// Run the backup, restore, export or token command instead of the terminal UI, if one was given.
+	// They run before the startup housekeeping below, so they never migrate or re-encrypt
+	// the stored courses as a side effect
+	if runCommand(base, history, tokens, timeouts(*loadTimeout, *writeTimeout), *keyFile) {
+		return
 	}
 
-	// Establish MongoDB connection
-	client, err := mongo.Connect(options.Client().ApplyURI(uri))
-	if err != nil {
-		panic(err)
+	// Display the application title/banner
+	fmt.Println(tui.RenderTitle())
+
+	// Bring stored courses written by older versions up to the current schema
+	migrate(base, *migrateDryRun, *loadTimeout)
+	if *migrateDryRun {
+		return
 	}
 
-	// Fetch table headers and course data from database
-	headers := api.GetTableHeaders(client)
-	courses := api.GetAllCourses(client)
+	// Let the database itself reject invalid courses written by other clients
+	ensureSchema(base, *loadTimeout)
+
+	// Keep working from a local copy of the MongoDB courses while the database is unreachable
+	offlineCache := ""
+	if _, isMongo := base.(*api.MongoStore); isMongo && *offline {
+		offlineCache = offlineCachePath(*cachePath)
+	}
+
+	// Encrypt the grades stored before a key or passphrase was given, so backups hold them sealed too
+	gradeCipher := openCipher(*keyFile, base, offlineCache, *loadTimeout)
+	if gradeCipher != nil {
+		sealGrades(base, gradeCipher, *loadTimeout)
+	}
+
+	// Keep grades encrypted in the history when a key or passphrase is given
+	if gradeCipher != nil {
+		history = api.WithHistoryEncryption(history, gradeCipher)
+	}
+	store := api.WithTimeouts(api.WithHistory(base, history), timeouts(*loadTimeout, *writeTimeout))
+
+	if offlineCache != "" {
+		store = openOfflineStore(store, offlineCache)
+	}
+
+	// Encrypt grades above every other layer, so the offline cache and outbox only hold them sealed
+	if gradeCipher != nil {
+		store = api.WithEncryption(store, gradeCipher)
+	}
+
+	// Permanently remove courses that have been in the trash for longer than the retention period;
+	// the purge is canceled on exit, before the store disconnects
+	stopPurge := startPurge(store, *trashRetention)
+	defer stopPurge()
+
+	// Serve the HTTP API instead of the terminal UI, on the same store the UI would use
+	if flag.Arg(0) == "serve" {
+		runServe(flag.Args()[1:], store, history, tokens, *loadTimeout)
+		return
+	}
+
+	// Serve the HTTP API in the background while the terminal UI runs, if asked to
+	stopServer := func() {}
+	if *serveAddr != "" {
+		stopServer = startServer(*serveAddr, store, history, tokens, *loadTimeout)
+	}
 
 	// Initialize and run the Bubble Tea program with the picker screen
-	p := tea.NewProgram(picker.InitialModel(headers, courses, client))
-	if _, err := p.Run(); err != nil {
+	model := picker.InitialModel(store, history)
+	model.RefreshInterval = *refreshInterval
+	model.Columns = columns
+	p := tea.NewProgram(model)
+	_, err = p.Run()
+
+	// Let requests in flight finish before the store goes away
+	stopServer()
+	if err != nil {
 		fmt.Printf("Alas, there's been an error: %v", err)
+		stopPurge()
+		closeStore()
 		os.Exit(1)
 	}
 }
+
+// disconnectTimeout bounds closing the MongoDB connections on exit.
+const disconnectTimeout = 5 * time.Second
+
+// timeouts builds the per-operation store timeouts from the command-line flags.
+func timeouts(load, write time.Duration) api.Timeouts {
+	return api.Timeouts{Load: load, Add: write, Update: write, Delete: write}
+}
+
+// offlineCachePath returns path, or the default location of the offline cache if path is empty.
+func offlineCachePath(path string) string {
+	if path != "" {
+		return path
+	}
+	defaultPath, err := api.DefaultCachePath()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return defaultPath
+}
+
+// openOfflineStore wraps a store with the offline cache at path.
+func openOfflineStore(store api.CourseStore, path string) api.CourseStore {
+	offline, err := api.NewOfflineStore(store, path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return offline
+}
+
+// openCipher creates the grade cipher from the keyfile at path or, without one, from the
+// UNIGRADES_PASSPHRASE environment variable and the salt kept by the store. With an offline
+// cache, the salt is copied into it, so the passphrase still works while the store is unreachable.
+// Returns nil if neither is set, leaving grades unencrypted.
+func openCipher(path string, store api.CourseStore, cachePath string, timeout time.Duration) *api.GradeCipher {
+	passphrase := os.Getenv("UNIGRADES_PASSPHRASE")
+	var c *api.GradeCipher
+	var err error
+	switch {
+	case path != "" && passphrase != "":
+		log.Fatal("Use either -key-file or UNIGRADES_PASSPHRASE to encrypt grades, not both")
+	case path != "":
+		c, err = api.NewKeyfileCipher(path)
+	case passphrase != "":
+		keeper, ok := store.(api.SaltKeeper)
+		if !ok {
+			log.Fatal("The selected store can't keep the salt of a passphrase, use -key-file instead")
+		}
+		ctx, cancel := startupContext(timeout)
+		defer cancel()
+		var salt []byte
+		var saltErr error
+		if cachePath != "" {
+			salt, saltErr = api.CachedPassphraseSalt(ctx, keeper, cachePath)
+		} else {
+			salt, saltErr = api.PassphraseSalt(ctx, keeper)
+		}
+		if saltErr != nil {
+			log.Fatalf("Failed to read the encryption salt: %v", saltErr)
+		}
+		c, err = api.NewPassphraseCipher(passphrase, salt)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	return c
+}
+
+// sealGrades encrypts the grades that were stored before encryption was turned on.
+// Like the migrations, it is skipped with a warning while the database is unreachable.
+func sealGrades(store api.CourseStore, c *api.GradeCipher, timeout time.Duration) {
+	migratable, ok := store.(api.Migratable)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := startupContext(timeout)
+	defer cancel()
+	sealed, err := api.SealStoredGrades(ctx, migratable, c)
+	if err != nil && unreachable(err) {
+		fmt.Printf("Warning: skipped encrypting stored grades, the database is unreachable: %v\n", err)
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to encrypt stored grades: %v", err)
+	}
+	if sealed > 0 {
+		fmt.Printf("Encrypted the grades of %d stored course(s)\n", sealed)
+	}
+}
+
+// startupContext bounds a startup task by the load timeout, unless the timeout is disabled.
+func startupContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// unreachable reports whether a startup task failed because the database can't be reached.
+func unreachable(err error) bool {
+	return errors.Is(err, api.ErrUnavailable) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// migrate runs the pending schema migrations of stores that persist their courses.
+// Applied migrations are reported on stdout; a dry run only reports what would change.
+// A failed migration stops the application, since the stored courses can't be read reliably,
+// unless the database is unreachable: then the migrations run on the next start.
+func migrate(store api.CourseStore, dryRun bool, timeout time.Duration) {
+	migratable, ok := store.(api.Migratable)
+	if !ok {
+		if dryRun {
+			fmt.Println("The selected store keeps no stored courses to migrate")
+		}
+		return
+	}
+
+	ctx, cancel := startupContext(timeout)
+	defer cancel()
+	report, err := api.Migrate(ctx, migratable, dryRun)
+	if err != nil && unreachable(err) && !dryRun {
+		fmt.Printf("Warning: skipped schema migrations, the database is unreachable: %v\n", err)
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to migrate stored courses: %v", err)
+	}
+	if dryRun || len(report.Results) > 0 {
+		fmt.Println(report)
+	}
+}
+
+// ensureSchema installs the course validator and indexes on stores that support it and
+// prints any drift it found. Failures are only reported: the app validates courses itself,
+// so it keeps working with a database it isn't allowed to change.
+func ensureSchema(store api.CourseStore, timeout time.Duration) {
+	enforcer, ok := store.(api.SchemaEnforcer)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := startupContext(timeout)
+	defer cancel()
+	report, err := enforcer.EnsureSchema(ctx)
+	if err != nil {
+		fmt.Printf("Warning: failed to check the database schema: %v\n", err)
+		return
+	}
+	if len(report.Drift) > 0 {
+		fmt.Println(report)
+	}
+}
+
+// startPurge permanently removes the courses of every university that were trashed
+// more than retention ago, in the background. Failures are ignored because the next
+// start simply tries again. A zero retention keeps the trash forever.
+// Returns a function that cancels the purge and waits until it has stopped.
+func startPurge(store api.CourseStore, retention time.Duration) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		purgeTrash(ctx, store, retention)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// purgeTrash removes the courses trashed more than retention ago, one university at a time,
+// until ctx is canceled.
+func purgeTrash(ctx context.Context, store api.CourseStore, retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	before := time.Now().Add(-retention)
+	for _, uni := range university.Names() {
+		if ctx.Err() != nil {
+			return
+		}
+		store.PurgeTrash(ctx, uni, before)
+	}
+}
+
+// openStore creates the course store for the given backend name, together with
+// the history store that keeps the change log and the token store that keeps the
+// API tokens in the same backend. The memory backend keeps its tokens in the default
+// tokens file, so they outlive the process that created them.
+// An empty name picks "mongo" when MONGODB_URI is set and the offline "file" store otherwise.
+// The "mongo" backend requires the MONGODB_URI environment variable.
+// The returned function disconnects from the database once the stores are no longer used.
+func openStore(kind, filePath string) (api.CourseStore, api.HistoryStore, api.TokenStore, func()) {
+	// Retrieve MongoDB connection URI from environment
+	uri := os.Getenv("MONGODB_URI")
+	if kind == "" {
+		kind = "file"
+		if uri != "" {
+			kind = "mongo"
+		}
+	}
+
+	switch kind {
+	case "memory":
+		// In-memory store, no database needed (data is lost on exit)
+		return api.NewMemoryStore(), api.NewMemoryHistory(), openFileTokens(""), func() {}
+	case "file":
+		// Local file store under the user's config directory, works offline
+		if filePath == "" {
+			path, err := api.DefaultFilePath()
+			if err != nil {
+				log.Fatal(err)
+			}
+			filePath = path
+		}
+		store, err := api.NewFileStore(filePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		history, err := api.NewFileHistory(api.HistoryFilePath(filePath))
+		if err != nil {
+			log.Fatal(err)
+		}
+		return store, history, openFileTokens(filePath), func() {}
+	case "mongo":
+		if uri == "" {
+			log.Fatal("Set your 'MONGODB_URI' environment variable. " +
+				"See: www.mongodb.com/docs/drivers/go/current/" +
+				"usage-examples/#environment-variable")
+		}
+
+		// Establish MongoDB connection
+		client, err := mongo.Connect(options.Client().ApplyURI(uri))
+		if err != nil {
+			log.Fatalf("Failed to set up MongoDB client: %v", err)
+		}
+		disconnect := func() {
+			ctx, cancel := context.WithTimeout(context.Background(), disconnectTimeout)
+			defer cancel()
+			client.Disconnect(ctx)
+		}
+		return api.NewMongoStore(client), api.NewMongoHistory(client), api.NewMongoTokens(client), disconnect
+	default:
+		log.Fatalf("Unknown store %q. Valid stores are: mongo, file, memory", kind)
+		return nil, nil, nil, nil
+	}
+}
+
+// openFileTokens opens the tokens file kept next to the data file at filePath, or next to
+// the default data file if filePath is empty.
+func openFileTokens(filePath string) api.TokenStore {
+	if filePath == "" {
+		path, err := api.DefaultFilePath()
+		if err != nil {
+			log.Fatal(err)
+		}
+		filePath = path
+	}
+	tokens, err := api.NewFileTokens(api.TokensFilePath(filePath))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return tokens
+}
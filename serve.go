@@ -0,0 +1,85 @@
+// Package main is the entry point for the UniGrades application.
+package main
+
+import (
+	// Standard library imports
+	"context"   // Server lifetime
+	"flag"      // Command-line flag parsing
+	"fmt"       // Formatted I/O
+	"log"       // Logging support
+	"net"       // Listening before the terminal UI starts
+	"os"        // Interrupt signal
+	"os/signal" // Ctrl+C handling
+	"syscall"   // Termination signal
+	"time"      // Startup timeout
+
+	// Internal packages
+	"UniGrades/internal/api" // HTTP API
+)
+
+// defaultAddr is where the HTTP API listens unless told otherwise. It only accepts
+// connections from this machine.
+const defaultAddr = "localhost:8080"
+
+// runServe implements "unigrades serve [-addr ADDR]": it serves the HTTP API without the
+// terminal UI until Ctrl+C (or SIGTERM), then lets requests in flight finish.
+// Every request needs one of the tokens created with "unigrades token create".
+// Listing the tokens at startup is bounded by timeout.
+func runServe(args []string, store api.CourseStore, history api.HistoryStore, tokens api.TokenStore, timeout time.Duration) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", defaultAddr, "address to serve the HTTP API on (e.g. :8080 for every interface)")
+	fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	api.InitServer(store, history, tokens)
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", *addr, err)
+	}
+	fmt.Printf("Serving the HTTP API on http://%s (Ctrl+C to stop)\n", ln.Addr())
+	warnWithoutTokens(tokens, timeout)
+	if err := api.Serve(ctx, ln); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Server stopped")
+}
+
+// startServer serves the HTTP API at addr in the background while the terminal UI runs,
+// sharing its store, so changes made through either show up in both. The address is
+// claimed before the UI starts, so a port already in use is reported right away.
+// Listing the tokens at startup is bounded by timeout.
+// Returns a function that shuts the server down and waits until it has stopped.
+func startServer(addr string, store api.CourseStore, history api.HistoryStore, tokens api.TokenStore, timeout time.Duration) func() {
+	api.InitServer(store, history, tokens)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", addr, err)
+	}
+	fmt.Printf("Serving the HTTP API on http://%s\n", ln.Addr())
+	warnWithoutTokens(tokens, timeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- api.Serve(ctx, ln)
+	}()
+	return func() {
+		cancel()
+		if err := <-done; err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+}
+
+// warnWithoutTokens points out that the server rejects every request until a token is created.
+// The tokens are listed within timeout, so an unreachable token store can't hold up startup;
+// if they can't be listed, no warning is shown.
+func warnWithoutTokens(tokens api.TokenStore, timeout time.Duration) {
+	ctx, cancel := startupContext(timeout)
+	defer cancel()
+	if list, err := tokens.ListTokens(ctx); err == nil && len(list) == 0 {
+		fmt.Println("Warning: no API tokens yet, so every request is rejected. Create one with: unigrades token create -scope write NAME")
+	}
+}
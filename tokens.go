@@ -0,0 +1,79 @@
+// Package main is the entry point for the UniGrades application.
+package main
+
+import (
+	// Standard library imports
+	"flag"           // Command-line flag parsing
+	"fmt"            // Formatted I/O
+	"log"            // Logging support
+	"os"             // Standard output for the token list
+	"strings"        // Token names with spaces
+	"text/tabwriter" // Aligned token list
+	"time"           // Timeout of the token store
+
+	// Internal packages
+	"UniGrades/internal/api" // API tokens
+)
+
+// tokenUsage describes the token subcommands.
+const tokenUsage = "Usage: unigrades token create [-scope read|write] NAME | token list | token revoke ID"
+
+// runToken implements "unigrades token create|list|revoke", which manage the API tokens
+// the HTTP server accepts. A new token's secret is printed once and never stored.
+func runToken(args []string, tokens api.TokenStore, timeout time.Duration) {
+	if len(args) == 0 {
+		log.Fatal(tokenUsage)
+	}
+	ctx, cancel := startupContext(timeout)
+	defer cancel()
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("token create", flag.ExitOnError)
+		scopeName := fs.String("scope", "read", "what the token allows: read (GET requests only) or write (everything)")
+		fs.Parse(args[1:])
+		if fs.NArg() == 0 {
+			log.Fatal(tokenUsage)
+		}
+		scope, err := api.ParseScope(*scopeName)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		secret, token, err := api.CreateToken(ctx, tokens, strings.Join(fs.Args(), " "), scope)
+		if err != nil {
+			log.Fatalf("Failed to create the token: %v", err)
+		}
+		fmt.Printf("Created %s token '%s' (ID %s). Send it as \"Authorization: Bearer <token>\";\n", token.Scope, token.Name, token.ID)
+		fmt.Println("it is shown only this once:")
+		fmt.Println(secret)
+
+	case "list":
+		list, err := tokens.ListTokens(ctx)
+		if err != nil {
+			log.Fatalf("Failed to list the tokens: %v", err)
+		}
+		if len(list) == 0 {
+			fmt.Println("No API tokens yet. Create one with: unigrades token create -scope read NAME")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSCOPE\tCREATED")
+		for _, t := range list {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Scope, t.CreatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+
+	case "revoke":
+		if len(args) != 2 {
+			log.Fatal(tokenUsage)
+		}
+		if err := tokens.RevokeToken(ctx, args[1]); err != nil {
+			log.Fatalf("Failed to revoke the token: %v", err)
+		}
+		fmt.Printf("Revoked token %s; it is no longer accepted\n", args[1])
+
+	default:
+		log.Fatal(tokenUsage)
+	}
+}